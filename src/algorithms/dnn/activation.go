@@ -0,0 +1,219 @@
+package dnn
+
+import "math"
+
+// Activation is a layer activation function. Forward writes the activations
+// for the pre-activation values z into dst. Backward receives the gradient of
+// the loss with respect to the activations and writes the gradient with
+// respect to z into dst. It is handed both z and the activations a computed
+// by Forward, so that activations whose derivative is most naturally
+// expressed in terms of their output, or which couple the whole vector like
+// Softmax, can apply their full Jacobian.
+type Activation interface {
+    Forward(dst, z Vector)
+    Backward(dst, z, a, grad Vector)
+}
+
+// Elementwise adapts a plain function and its derivative with respect to z
+// into an Activation.
+type Elementwise struct {
+    Fn    func(float32) float32
+    Deriv func(float32) float32
+}
+
+func (f Elementwise) Forward(dst, z Vector) {
+    for i := range z {
+        dst[i] = f.Fn(z[i])
+    }
+}
+
+func (f Elementwise) Backward(dst, z, a, grad Vector) {
+    for i := range z {
+        dst[i] = grad[i] * f.Deriv(z[i])
+    }
+}
+
+// SigmoidActivation is the logistic function 1/(1+e^-z).
+type SigmoidActivation struct{}
+
+func (SigmoidActivation) Forward(dst, z Vector) {
+    for i := range z {
+        dst[i] = Sigmoid(z[i])
+    }
+}
+
+func (SigmoidActivation) Backward(dst, z, a, grad Vector) {
+    for i := range a {
+        dst[i] = grad[i] * a[i] * (1 - a[i])
+    }
+}
+
+// Linear is the identity activation, used for regression outputs.
+type Linear struct{}
+
+func (Linear) Forward(dst, z Vector) {
+    copy(dst, z)
+}
+
+func (Linear) Backward(dst, z, a, grad Vector) {
+    copy(dst, grad)
+}
+
+// ReLU is the rectified linear unit max(0, z).
+type ReLU struct{}
+
+func (ReLU) Forward(dst, z Vector) {
+    for i := range z {
+        if z[i] > 0 {
+            dst[i] = z[i]
+        } else {
+            dst[i] = 0
+        }
+    }
+}
+
+func (ReLU) Backward(dst, z, a, grad Vector) {
+    for i := range z {
+        if z[i] > 0 {
+            dst[i] = grad[i]
+        } else {
+            dst[i] = 0
+        }
+    }
+}
+
+// LeakyReLU lets a small gradient Alpha through for negative inputs. Alpha
+// defaults to 0.01 when left at zero.
+type LeakyReLU struct {
+    Alpha float32
+}
+
+func (f LeakyReLU) alpha() float32 {
+    if f.Alpha == 0 {
+        return 0.01
+    }
+    return f.Alpha
+}
+
+func (f LeakyReLU) Forward(dst, z Vector) {
+    alpha := f.alpha()
+    for i := range z {
+        if z[i] > 0 {
+            dst[i] = z[i]
+        } else {
+            dst[i] = alpha * z[i]
+        }
+    }
+}
+
+func (f LeakyReLU) Backward(dst, z, a, grad Vector) {
+    alpha := f.alpha()
+    for i := range z {
+        if z[i] > 0 {
+            dst[i] = grad[i]
+        } else {
+            dst[i] = alpha * grad[i]
+        }
+    }
+}
+
+// Tanh is the hyperbolic tangent.
+type Tanh struct{}
+
+func (Tanh) Forward(dst, z Vector) {
+    for i := range z {
+        dst[i] = float32(math.Tanh(float64(z[i])))
+    }
+}
+
+func (Tanh) Backward(dst, z, a, grad Vector) {
+    for i := range a {
+        dst[i] = grad[i] * (1 - a[i]*a[i])
+    }
+}
+
+// ELU is the exponential linear unit, Alpha*(e^z-1) for negative inputs.
+// Alpha defaults to 1 when left at zero.
+type ELU struct {
+    Alpha float32
+}
+
+func (f ELU) alpha() float32 {
+    if f.Alpha == 0 {
+        return 1
+    }
+    return f.Alpha
+}
+
+func (f ELU) Forward(dst, z Vector) {
+    alpha := f.alpha()
+    for i := range z {
+        if z[i] > 0 {
+            dst[i] = z[i]
+        } else {
+            dst[i] = alpha * float32(math.Expm1(float64(z[i])))
+        }
+    }
+}
+
+func (f ELU) Backward(dst, z, a, grad Vector) {
+    alpha := f.alpha()
+    for i := range z {
+        if z[i] > 0 {
+            dst[i] = grad[i]
+        } else {
+            dst[i] = grad[i] * (a[i] + alpha)
+        }
+    }
+}
+
+// GELU is the Gaussian error linear unit, using the usual tanh
+// approximation.
+type GELU struct{}
+
+const geluC = 0.7978845608028654 // sqrt(2/pi)
+
+func (GELU) Forward(dst, z Vector) {
+    for i := range z {
+        x := float64(z[i])
+        dst[i] = float32(0.5 * x * (1 + math.Tanh(geluC*(x+0.044715*x*x*x))))
+    }
+}
+
+func (GELU) Backward(dst, z, a, grad Vector) {
+    for i := range z {
+        x := float64(z[i])
+        t := math.Tanh(geluC * (x + 0.044715*x*x*x))
+        dt := (1 - t*t) * geluC * (1 + 3*0.044715*x*x)
+        dst[i] = grad[i] * float32(0.5*(1+t)+0.5*x*dt)
+    }
+}
+
+// Softmax normalizes the whole vector into a probability distribution. Its
+// Jacobian couples every output with every input, which Backward applies in
+// full as a_i * (g_i - sum_k g_k a_k).
+type Softmax struct{}
+
+func (Softmax) Forward(dst, z Vector) {
+    max := z[0]
+    for _, v := range z[1:] {
+        if v > max {
+            max = v
+        }
+    }
+    var sum float32
+    for i := range z {
+        dst[i] = float32(math.Exp(float64(z[i] - max)))
+        sum += dst[i]
+    }
+    for i := range dst[:len(z)] {
+        dst[i] /= sum
+    }
+}
+
+func (Softmax) Backward(dst, z, a, grad Vector) {
+    dot := DotProduct(grad, a)
+    for i := range a {
+        dst[i] = a[i] * (grad[i] - dot)
+    }
+}
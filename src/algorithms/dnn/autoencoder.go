@@ -0,0 +1,74 @@
+package dnn
+
+// Autoencoder is a Network trained to reproduce its inputs through a narrower
+// code. The first layers form the encoder, whose output is the code returned
+// by Encode, and the remaining ones the decoder. Training options such as
+// LearningRate, Optimizer or Strategy are set on the embedded Network; Loss
+// defaults to MeanSquaredError.
+type Autoencoder struct {
+    *Network
+    encoderLayers int
+}
+
+// NewAutoencoder stacks the encoder and decoder layers over inputs of width
+// inputWidth. The decoder must end with a layer of width inputWidth, usually
+// a Linear Dense layer for standardized features.
+func NewAutoencoder(inputWidth int, encoder, decoder []Layer) *Autoencoder {
+    layers := make([]Layer, 0, len(encoder)+len(decoder))
+    layers = append(layers, encoder...)
+    layers = append(layers, decoder...)
+    return &Autoencoder{
+        Network:       &Network{InputWidth: inputWidth, Layers: layers},
+        encoderLayers: len(encoder),
+    }
+}
+
+// Train fits the network to reconstruct the inputs, which act as their own
+// labels, and returns the training History
+func (a *Autoencoder) Train(epochs int, inputs Frame) (*History, error) {
+    return a.Network.Train(epochs, inputs, inputs)
+}
+
+// Encode maps every input row to its code, the output of the encoder layers
+// in inference mode. Like Predict, an autoencoder that was never trained is
+// initialized first.
+func (a *Autoencoder) Encode(inputs Frame) Frame {
+    if !a.initialized {
+        a.Initialize()
+    }
+    activations := fromFrameWidth(inputs, a.InputWidth)
+    for _, layer := range a.Layers[:a.encoderLayers] {
+        activations = layer.Forward(activations, false)
+    }
+    return activations.ToFrame()
+}
+
+// Reconstruct returns the reconstruction of every input row
+func (a *Autoencoder) Reconstruct(inputs Frame) Frame {
+    return a.Predict(inputs)
+}
+
+// ReconstructionError returns the mean squared difference between every input
+// row and its reconstruction. Rows unlike those seen in training reconstruct
+// poorly, so the error doubles as an anomaly score. An autoencoder that was
+// never trained is initialized first, which also defaults its Strategy.
+func (a *Autoencoder) ReconstructionError(inputs Frame) []float64 {
+    if !a.initialized {
+        a.Initialize()
+    }
+    x := fromFrameWidth(inputs, a.InputWidth)
+    reconstructions := a.PredictMatrix(x)
+    scores := make([]float64, x.Rows)
+    a.Strategy.Run(x.Rows, func(start, end int) {
+        for i := start; i < end; i++ {
+            var sum float64
+            in, out := x.Row(i), reconstructions.Row(i)
+            for j := range in {
+                diff := float64(out[j] - in[j])
+                sum += diff * diff
+            }
+            scores[i] = sum / float64(x.Cols)
+        }
+    })
+    return scores
+}
@@ -0,0 +1,26 @@
+package dnn
+
+import "testing"
+
+func TestAutoencoderUntrained(t *testing.T) {
+    inputs, _ := gradientCheckData(5, 4, 1, MeanSquaredError{})
+    a := NewAutoencoder(4, []Layer{&Dense{Width: 2, Activation: Tanh{}}}, []Layer{&Dense{Width: 4, Activation: Linear{}}})
+
+    scores := a.ReconstructionError(inputs)
+    if len(scores) != len(inputs) {
+        t.Fatalf("ReconstructionError returned %d scores, want %d", len(scores), len(inputs))
+    }
+    for i, score := range scores {
+        if score < 0 {
+            t.Errorf("row %d has negative reconstruction error %g", i, score)
+        }
+    }
+
+    codes := a.Encode(inputs)
+    if len(codes) != len(inputs) || len(codes[0]) != 2 {
+        t.Errorf("Encode returned %d codes of width %d, want %d of width 2", len(codes), len(codes[0]), len(inputs))
+    }
+    if scores := a.ReconstructionError(Frame{}); len(scores) != 0 {
+        t.Errorf("ReconstructionError(Frame{}) = %v, want no scores", scores)
+    }
+}
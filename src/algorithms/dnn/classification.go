@@ -0,0 +1,240 @@
+package dnn
+
+import (
+    "fmt"
+    "math"
+    "strings"
+)
+
+// NewFrame converts rows of float64 features into a Frame
+func NewFrame(rows [][]float64) Frame {
+    frame := make(Frame, len(rows))
+    for i, row := range rows {
+        frame[i] = make(Vector, len(row))
+        for j, val := range row {
+            frame[i][j] = float32(val)
+        }
+    }
+    return frame
+}
+
+// LabelFrame encodes binary class labels, or regression targets, as a Frame
+// of width 1, for networks with a single sigmoid or linear output
+func LabelFrame(labels []float64) Frame {
+    frame := make(Frame, len(labels))
+    for i, label := range labels {
+        frame[i] = Vector{float32(label)}
+    }
+    return frame
+}
+
+// OneHot encodes class labels 0..classes-1 as one-hot rows, for networks with
+// one output per class. When classes is not positive it is taken as one more
+// than the largest label.
+func OneHot(labels []float64, classes int) (Frame, error) {
+    if classes <= 0 {
+        for _, label := range labels {
+            classes = max(classes, classLabel(label)+1)
+        }
+    }
+    frame := make(Frame, len(labels))
+    for i, label := range labels {
+        class := classLabel(label)
+        if class < 0 || class >= classes {
+            return nil, fmt.Errorf("label %v at row %d is not a class in [0, %d)", label, i, classes)
+        }
+        frame[i] = make(Vector, classes)
+        frame[i][class] = 1
+    }
+    return frame, nil
+}
+
+// Argmax returns the index of the largest entry of v, the first one on ties
+func Argmax(v Vector) int {
+    best := 0
+    for i := range v {
+        if v[i] > v[best] {
+            best = i
+        }
+    }
+    return best
+}
+
+// ClassOf returns the class encoded by an output or label row. A single
+// output is thresholded at 0.5, wider rows pick their largest entry.
+func ClassOf(row Vector) int {
+    if len(row) == 1 {
+        if row[0] >= 0.5 {
+            return 1
+        }
+        return 0
+    }
+    return Argmax(row)
+}
+
+// PredictProba returns one probability per class for every input row. The
+// output layer is expected to produce probabilities already (a softmax over
+// several outputs or a single sigmoid); a single output p is expanded into
+// the two columns 1-p and p.
+func (n *Network) PredictProba(inputs Frame) Frame {
+    predictions := n.Predict(inputs)
+    if n.outputWidth != 1 {
+        return predictions
+    }
+    for i, prediction := range predictions {
+        predictions[i] = Vector{1 - prediction[0], prediction[0]}
+    }
+    return predictions
+}
+
+// PredictClass returns the predicted class of every input row
+func (n *Network) PredictClass(inputs Frame) []int {
+    predictions := n.Predict(inputs)
+    classes := make([]int, len(predictions))
+    for i, prediction := range predictions {
+        classes[i] = ClassOf(prediction)
+    }
+    return classes
+}
+
+// ConfusionMatrix counts predictions per class: entry [actual][predicted]
+// holds how many rows of class actual were predicted as class predicted
+type ConfusionMatrix [][]int
+
+// NewConfusionMatrix builds the confusion matrix of predicted against actual
+// classes 0..classes-1. When classes is not positive it is taken as one more
+// than the largest class seen.
+func NewConfusionMatrix(predicted, actual []int, classes int) (ConfusionMatrix, error) {
+    if len(predicted) != len(actual) {
+        return nil, fmt.Errorf("predicted and actual classes must have the same length, got %d and %d", len(predicted), len(actual))
+    }
+    if classes <= 0 {
+        for i := range actual {
+            classes = max(classes, predicted[i]+1, actual[i]+1)
+        }
+    }
+    cm := make(ConfusionMatrix, classes)
+    for i := range cm {
+        cm[i] = make([]int, classes)
+    }
+    for i := range actual {
+        if actual[i] < 0 || actual[i] >= classes || predicted[i] < 0 || predicted[i] >= classes {
+            return nil, fmt.Errorf("row %d has classes outside [0, %d)", i, classes)
+        }
+        cm[actual[i]][predicted[i]]++
+    }
+    return cm, nil
+}
+
+// Accuracy returns the fraction of rows on the diagonal
+func (cm ConfusionMatrix) Accuracy() float64 {
+    correct, total := 0, 0
+    for i, row := range cm {
+        for j, count := range row {
+            total += count
+            if i == j {
+                correct += count
+            }
+        }
+    }
+    if total == 0 {
+        return 0
+    }
+    return float64(correct) / float64(total)
+}
+
+// Precision returns the fraction of rows predicted as class that belong to it
+func (cm ConfusionMatrix) Precision(class int) float64 {
+    predicted := 0
+    for i := range cm {
+        predicted += cm[i][class]
+    }
+    return ratio(cm[class][class], predicted)
+}
+
+// Recall returns the fraction of rows of class that were predicted as such
+func (cm ConfusionMatrix) Recall(class int) float64 {
+    actual := 0
+    for _, count := range cm[class] {
+        actual += count
+    }
+    return ratio(cm[class][class], actual)
+}
+
+// F1 returns the harmonic mean of the precision and recall of class
+func (cm ConfusionMatrix) F1(class int) float64 {
+    p, r := cm.Precision(class), cm.Recall(class)
+    if p+r == 0 {
+        return 0
+    }
+    return 2 * p * r / (p + r)
+}
+
+// MacroF1 returns the F1 score averaged over classes with equal weight
+func (cm ConfusionMatrix) MacroF1() float64 {
+    if len(cm) == 0 {
+        return 0
+    }
+    var sum float64
+    for class := range cm {
+        sum += cm.F1(class)
+    }
+    return sum / float64(len(cm))
+}
+
+// String renders the matrix with actual classes as rows and predicted classes
+// as columns
+func (cm ConfusionMatrix) String() string {
+    width := 1
+    for _, row := range cm {
+        for _, count := range row {
+            width = max(width, len(fmt.Sprint(count)))
+        }
+    }
+    width = max(width, len(fmt.Sprint(len(cm)-1))) + 1
+
+    var b strings.Builder
+    fmt.Fprintf(&b, "%*s", width+2, "")
+    for j := range cm {
+        fmt.Fprintf(&b, "%*d", width, j)
+    }
+    b.WriteByte('\n')
+    for i, row := range cm {
+        fmt.Fprintf(&b, "%*d |", width, i)
+        for _, count := range row {
+            fmt.Fprintf(&b, "%*d", width, count)
+        }
+        b.WriteByte('\n')
+    }
+    return b.String()
+}
+
+func ratio(num, den int) float64 {
+    if den == 0 {
+        return 0
+    }
+    return float64(num) / float64(den)
+}
+
+// labelAccuracy compares the classes of prediction rows against label rows
+func labelAccuracy(predictions, labels *Matrix) float64 {
+    if labels.Rows == 0 {
+        return 0
+    }
+    correct := 0
+    for i := 0; i < predictions.Rows; i++ {
+        if ClassOf(predictions.Row(i)) == ClassOf(labels.Row(i)) {
+            correct++
+        }
+    }
+    return float64(correct) / float64(labels.Rows)
+}
+
+// classLabel converts a class label held as a float into a class index,
+// returning -1 when it is not a whole non-negative number
+func classLabel(label float64) int {
+    if label < 0 || label != math.Trunc(label) {
+        return -1
+    }
+    return int(label)
+}
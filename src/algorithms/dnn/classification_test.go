@@ -0,0 +1,34 @@
+package dnn
+
+import "testing"
+
+func TestPredictUntrained(t *testing.T) {
+    inputs, _ := gradientCheckData(4, 3, 1, BinaryCrossEntropy{})
+    cases := []struct {
+        name    string
+        output  *Dense
+        classes int
+    }{
+        {name: "sigmoid", output: &Dense{Width: 1, Activation: SigmoidActivation{}}, classes: 2},
+        {name: "softmax", output: &Dense{Width: 3, Activation: Softmax{}}, classes: 3},
+    }
+    for _, c := range cases {
+        n := &Network{InputWidth: 3, Layers: []Layer{&Dense{Width: 4}, c.output}, Seed: 1}
+
+        probabilities := n.PredictProba(inputs)
+        if len(probabilities) != len(inputs) {
+            t.Fatalf("%s: PredictProba returned %d rows, want %d", c.name, len(probabilities), len(inputs))
+        }
+        for i, row := range probabilities {
+            if len(row) != c.classes {
+                t.Errorf("%s: row %d has %d probabilities, want %d", c.name, i, len(row), c.classes)
+            }
+        }
+
+        for i, class := range n.PredictClass(inputs) {
+            if class < 0 || class >= c.classes {
+                t.Errorf("%s: row %d predicted class %d outside [0, %d)", c.name, i, class, c.classes)
+            }
+        }
+    }
+}
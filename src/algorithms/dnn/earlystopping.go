@@ -0,0 +1,68 @@
+package dnn
+
+// EarlyStopping watches the loss on the validation set of the Network after
+// every epoch and stops training once it has failed to improve by more than
+// MinDelta for Patience consecutive epochs. The weights from the best epoch
+// are restored when training ends. Patience defaults to 5 when left at zero.
+type EarlyStopping struct {
+    Patience int
+    MinDelta float32
+
+    best    float32
+    wait    int
+    hasBest bool
+}
+
+// reset prepares the tracker for a new training run
+func (es *EarlyStopping) reset() {
+    es.best = 0
+    es.wait = 0
+    es.hasBest = false
+}
+
+// observe records the validation loss of an epoch and reports whether it is
+// a new best, and whether training should stop
+func (es *EarlyStopping) observe(loss float32) (improved bool, stop bool) {
+    patience := es.Patience
+    if patience == 0 {
+        patience = 5
+    }
+    if !es.hasBest || loss < es.best-es.MinDelta {
+        es.best = loss
+        es.wait = 0
+        es.hasBest = true
+        return true, false
+    }
+    es.wait++
+    return false, es.wait >= patience
+}
+
+// layerSnapshot is a copy of the trainable parameters and running
+// statistics of a single layer
+type layerSnapshot struct {
+    params  Frame
+    running Frame
+}
+
+// runningStatsLayer is implemented by layers that track statistics outside
+// of their trainable parameters, like BatchNorm
+type runningStatsLayer interface {
+    runningStats() []Vector
+}
+
+func cloneFrame(f Frame) Frame {
+    if f == nil {
+        return nil
+    }
+    result := make(Frame, len(f))
+    for i := range f {
+        result[i] = append(Vector(nil), f[i]...)
+    }
+    return result
+}
+
+func copyFrame(dst, src Frame) {
+    for i := range src {
+        copy(dst[i], src[i])
+    }
+}
@@ -0,0 +1,163 @@
+package dnn
+
+import (
+    "fmt"
+    "math"
+    "strings"
+)
+
+// GradientCheck compares the analytic gradients computed by backpropagation
+// against central finite differences of the loss, one parameter at a time.
+// Epsilon is the perturbation applied to each parameter and defaults to
+// 1e-2, large enough for the loss difference to stand out of float32
+// rounding; Tolerance is the largest relative error accepted for a layer and
+// also defaults to 1e-2. Relative errors are taken against at least
+// gradientFloor, so that gradients close to zero are compared in absolute
+// terms instead of against float32 noise. Check is meant to be called from
+// tests:
+//
+//    _, err := dnn.GradientCheck{}.Check(network, inputs, labels)
+//    if err != nil {
+//        t.Fatal(err)
+//    }
+type GradientCheck struct {
+    Epsilon   float32
+    Tolerance float64
+}
+
+// gradientFloor bounds the denominator of the relative error. Finite
+// differences of a float32 loss carry an absolute error of about 1e-5 with
+// the default epsilon, which would dominate the relative error of smaller
+// gradients.
+const gradientFloor = 1e-3
+
+// LayerGradient reports how far the analytic gradients of a layer are from
+// the numerical ones. MaxRelativeError is the worst |a-n| / max(|a|+|n|, 1e-3)
+// over the layer's parameters, found at parameter Param of vector Vector.
+type LayerGradient struct {
+    Layer            int
+    Name             string
+    Params           int
+    MaxRelativeError float64
+    Vector           int
+    Param            int
+    Analytic         float64
+    Numerical        float64
+}
+
+// String formats the report on a single line
+func (g LayerGradient) String() string {
+    return fmt.Sprintf(
+        "layer %d (%s): %d params, max relative error %.3e at [%d][%d] (analytic %.6g, numerical %.6g)",
+        g.Layer, g.Name, g.Params, g.MaxRelativeError, g.Vector, g.Param, g.Analytic, g.Numerical,
+    )
+}
+
+// Check runs the network over inputs and labels as a single batch in training
+// mode and returns the report of every layer, in order. The objective is the
+// loss averaged over the rows plus the weight penalties, as minimized by
+// Train. Dropout masks are frozen while checking so that the loss is
+// deterministic, and parameters and running statistics are left as they
+// were. An error is returned when any layer exceeds the tolerance.
+func (c GradientCheck) Check(n *Network, inputs, labels Frame) ([]LayerGradient, error) {
+    if err := n.check(inputs, labels); err != nil {
+        return nil, err
+    }
+    epsilon := orDefault(c.Epsilon, 1e-2)
+    tolerance := c.Tolerance
+    if tolerance == 0 {
+        tolerance = 1e-2
+    }
+
+    n.Initialize()
+    x, y := FromFrame(inputs), FromFrame(labels)
+    if err := n.checkOutputs(y); err != nil {
+        return nil, err
+    }
+    saved := n.snapshot(nil)
+    defer n.restore(saved)
+
+    n.backprop(x, y)
+    analytic := make([]Frame, len(n.Layers))
+    for i, layer := range n.Layers {
+        analytic[i] = cloneFrame(layer.Grads())
+    }
+
+    setFrozen(n, true)
+    defer setFrozen(n, false)
+
+    reports := make([]LayerGradient, len(n.Layers))
+    var failed []string
+    for i, layer := range n.Layers {
+        report := LayerGradient{Layer: i, Name: layerName(i, layer)}
+        for v, params := range layer.Params() {
+            for k := range params {
+                original := params[k]
+                params[k] = original + epsilon
+                plus := n.objective(x, y)
+                params[k] = original - epsilon
+                minus := n.objective(x, y)
+                params[k] = original
+
+                numerical := (plus - minus) / (2 * float64(epsilon))
+                a := float64(analytic[i][v][k])
+                err := math.Abs(a-numerical) / math.Max(math.Abs(a)+math.Abs(numerical), gradientFloor)
+                if report.Params == 0 || err > report.MaxRelativeError {
+                    report.MaxRelativeError = err
+                    report.Vector, report.Param = v, k
+                    report.Analytic, report.Numerical = a, numerical
+                }
+                report.Params++
+            }
+        }
+        reports[i] = report
+        if report.MaxRelativeError > tolerance {
+            failed = append(failed, report.String())
+        }
+    }
+
+    if failed != nil {
+        return reports, fmt.Errorf("gradient check failed with tolerance %g:\n%s", tolerance, strings.Join(failed, "\n"))
+    }
+    return reports, nil
+}
+
+// objective returns the training loss of the network over a batch
+func (n *Network) objective(inputs, labels *Matrix) float64 {
+    activations := inputs
+    for _, layer := range n.Layers {
+        activations = layer.Forward(activations, true)
+    }
+    var loss float64
+    for i := 0; i < activations.Rows; i++ {
+        loss += float64(n.Loss.Loss(activations.Row(i), labels.Row(i)))
+    }
+    return loss/float64(activations.Rows) + float64(n.penalty())
+}
+
+// setFrozen freezes or releases the masks of the Dropout layers
+func setFrozen(n *Network, frozen bool) {
+    for _, layer := range n.Layers {
+        if d, ok := layer.(*Dropout); ok {
+            d.frozen = frozen
+        }
+    }
+}
+
+// layerName returns the name of a layer, or its position and type when it
+// has none
+func layerName(i int, layer Layer) string {
+    var name string
+    switch l := layer.(type) {
+    case *Dense:
+        name = l.Name
+    case *Dropout:
+        name = l.Name
+    case *BatchNorm:
+        name = l.Name
+    }
+    if name == "" {
+        name = fmt.Sprintf("%T #%d", layer, i)
+    }
+    return name
+}
@@ -0,0 +1,109 @@
+package dnn
+
+import (
+    "math/rand"
+    "testing"
+)
+
+// gradientCheckData returns a small deterministic batch whose labels fit the
+// loss: one-hot rows for categorical losses, 0/1 for binary ones and real
+// values otherwise
+func gradientCheckData(rows, inputs, outputs int, loss Loss) (Frame, Frame) {
+    rng := rand.New(rand.NewSource(7))
+    x, y := make(Frame, rows), make(Frame, rows)
+    for i := range rows {
+        x[i] = make(Vector, inputs)
+        for j := range x[i] {
+            x[i][j] = float32(rng.NormFloat64())
+        }
+        y[i] = make(Vector, outputs)
+        switch loss.(type) {
+        case CategoricalCrossEntropy:
+            y[i][rng.Intn(outputs)] = 1
+        case BinaryCrossEntropy:
+            for j := range y[i] {
+                y[i][j] = float32(rng.Intn(2))
+            }
+        default:
+            for j := range y[i] {
+                y[i][j] = float32(rng.NormFloat64())
+            }
+        }
+    }
+    return x, y
+}
+
+func TestGradientCheck(t *testing.T) {
+    cases := []struct {
+        name    string
+        inputs  int
+        layers  func() []Layer
+        loss    Loss
+        outputs int
+    }{
+        {
+            name:   "tanh sigmoid binary cross-entropy",
+            inputs: 4,
+            layers: func() []Layer {
+                return []Layer{&Dense{Width: 5, Activation: Tanh{}}, &Dense{Width: 1, Activation: SigmoidActivation{}}}
+            },
+            loss:    BinaryCrossEntropy{},
+            outputs: 1,
+        },
+        {
+            name:   "relu softmax categorical cross-entropy",
+            inputs: 4,
+            layers: func() []Layer {
+                return []Layer{&Dense{Width: 6, Activation: ReLU{}}, &Dense{Width: 3, Activation: Softmax{}}}
+            },
+            loss:    CategoricalCrossEntropy{},
+            outputs: 3,
+        },
+        {
+            name:   "elu gelu linear mean squared error with l2",
+            inputs: 3,
+            layers: func() []Layer {
+                return []Layer{
+                    &Dense{Width: 5, Activation: ELU{}, L2: 1e-2},
+                    &Dense{Width: 4, Activation: GELU{}},
+                    &Dense{Width: 2, Activation: Linear{}},
+                }
+            },
+            loss:    MeanSquaredError{},
+            outputs: 2,
+        },
+        {
+            name:   "leaky relu dropout huber",
+            inputs: 3,
+            layers: func() []Layer {
+                return []Layer{&Dense{Width: 6, Activation: LeakyReLU{Alpha: 0.1}}, &Dropout{Rate: 0.3}, &Dense{Width: 2, Activation: Linear{}}}
+            },
+            loss:    Huber{},
+            outputs: 2,
+        },
+        {
+            name:   "batch norm sigmoid mean squared error",
+            inputs: 4,
+            layers: func() []Layer {
+                return []Layer{&Dense{Width: 5, Activation: Linear{}}, &BatchNorm{}, &Dense{Width: 1, Activation: SigmoidActivation{}}}
+            },
+            loss:    MeanSquaredError{},
+            outputs: 1,
+        },
+    }
+
+    for _, c := range cases {
+        for _, strategy := range []Strategy{Sequential{}, Sharded{Shards: 3}} {
+            n := &Network{InputWidth: c.inputs, Layers: c.layers(), Loss: c.loss, Strategy: strategy, Seed: 1}
+            x, y := gradientCheckData(8, c.inputs, c.outputs, c.loss)
+            reports, err := GradientCheck{}.Check(n, x, y)
+            if err != nil {
+                t.Errorf("%s (%T): %v", c.name, strategy, err)
+                continue
+            }
+            for _, report := range reports {
+                t.Logf("%s (%T): %s", c.name, strategy, report)
+            }
+        }
+    }
+}
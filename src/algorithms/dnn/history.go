@@ -0,0 +1,175 @@
+package dnn
+
+import (
+    "bytes"
+    "encoding/csv"
+    "encoding/json"
+    "io"
+    "math"
+    "os"
+    "strconv"
+    "time"
+)
+
+// History records every epoch of a training run, as returned by Train.
+// Validated reports whether the steps carry validation metrics, which
+// requires a validation set on the Network. BestEpoch is the epoch whose weights were kept,
+// the last one unless early stopping restored an earlier one, and Stopped
+// reports whether training ended before the requested number of epochs.
+type History struct {
+    Steps     []Step
+    Validated bool
+    BestEpoch int
+    Stopped   bool
+    Duration  time.Duration
+}
+
+// Loss returns the training loss of the last epoch, or zero when no epoch
+// was run
+func (h *History) Loss() float32 {
+    if len(h.Steps) == 0 {
+        return 0
+    }
+    return h.Steps[len(h.Steps)-1].Loss
+}
+
+// historyRecord is the exported form of a Step, with durations in seconds.
+// Metrics are held as jsonFloat so that a diverged run can still be written.
+type historyRecord struct {
+    Epoch              int          `json:"epoch"`
+    LearningRate       jsonFloat32  `json:"learning_rate"`
+    Loss               jsonFloat32  `json:"loss"`
+    Accuracy           jsonFloat    `json:"accuracy"`
+    R2                 jsonFloat    `json:"r2"`
+    ValidationLoss     *jsonFloat32 `json:"validation_loss,omitempty"`
+    ValidationAccuracy *jsonFloat   `json:"validation_accuracy,omitempty"`
+    ValidationR2       *jsonFloat   `json:"validation_r2,omitempty"`
+    Seconds            float64      `json:"seconds"`
+    SamplesPerSecond   jsonFloat    `json:"samples_per_second"`
+}
+
+// jsonFloat and jsonFloat32 encode NaN and infinities, which JSON numbers
+// cannot represent, as null
+type (
+    jsonFloat   float64
+    jsonFloat32 float32
+)
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+    return marshalFinite(float64(f), 64), nil
+}
+
+func (f jsonFloat32) MarshalJSON() ([]byte, error) {
+    return marshalFinite(float64(f), 32), nil
+}
+
+func marshalFinite(value float64, bitSize int) []byte {
+    if math.IsNaN(value) || math.IsInf(value, 0) {
+        return []byte("null")
+    }
+    return strconv.AppendFloat(nil, value, 'g', -1, bitSize)
+}
+
+func (h *History) records() []historyRecord {
+    records := make([]historyRecord, len(h.Steps))
+    for i, step := range h.Steps {
+        records[i] = historyRecord{
+            Epoch:            step.Epoch,
+            LearningRate:     jsonFloat32(step.LearningRate),
+            Loss:             jsonFloat32(step.Loss),
+            Accuracy:         jsonFloat(step.Accuracy),
+            R2:               jsonFloat(step.R2),
+            Seconds:          step.Duration.Seconds(),
+            SamplesPerSecond: jsonFloat(step.SamplesPerSecond),
+        }
+        if h.Validated {
+            validationLoss := jsonFloat32(step.ValidationLoss)
+            validationAccuracy := jsonFloat(step.ValidationAccuracy)
+            validationR2 := jsonFloat(step.ValidationR2)
+            records[i].ValidationLoss = &validationLoss
+            records[i].ValidationAccuracy = &validationAccuracy
+            records[i].ValidationR2 = &validationR2
+        }
+    }
+    return records
+}
+
+// WriteCSV writes one row per epoch under a header row. The validation
+// columns are present only when the history is Validated.
+func (h *History) WriteCSV(w io.Writer) error {
+    header := []string{"epoch", "learning_rate", "loss", "accuracy", "r2"}
+    if h.Validated {
+        header = append(header, "validation_loss", "validation_accuracy", "validation_r2")
+    }
+    header = append(header, "seconds", "samples_per_second")
+
+    cw := csv.NewWriter(w)
+    if err := cw.Write(header); err != nil {
+        return err
+    }
+    for _, r := range h.records() {
+        row := []string{
+            strconv.Itoa(r.Epoch),
+            formatFloat32(float32(r.LearningRate)),
+            formatFloat32(float32(r.Loss)),
+            formatFloat(float64(r.Accuracy)),
+            formatFloat(float64(r.R2)),
+        }
+        if h.Validated {
+            row = append(row,
+                formatFloat32(float32(*r.ValidationLoss)),
+                formatFloat(float64(*r.ValidationAccuracy)),
+                formatFloat(float64(*r.ValidationR2)),
+            )
+        }
+        row = append(row, formatFloat(r.Seconds), formatFloat(float64(r.SamplesPerSecond)))
+        if err := cw.Write(row); err != nil {
+            return err
+        }
+    }
+    cw.Flush()
+    return cw.Error()
+}
+
+// WriteJSON writes the history as a JSON object holding the run summary and
+// one entry per epoch. Metrics that are NaN or infinite, as in a diverged
+// run, are written as null.
+func (h *History) WriteJSON(w io.Writer) error {
+    data, err := json.MarshalIndent(struct {
+        Epochs    []historyRecord `json:"epochs"`
+        BestEpoch int             `json:"best_epoch"`
+        Stopped   bool            `json:"stopped"`
+        Seconds   float64         `json:"seconds"`
+    }{h.records(), h.BestEpoch, h.Stopped, h.Duration.Seconds()}, "", "  ")
+    if err != nil {
+        return err
+    }
+    _, err = w.Write(append(data, '\n'))
+    return err
+}
+
+// SaveCSV writes the history to a CSV file at path
+func (h *History) SaveCSV(path string) error {
+    var buf bytes.Buffer
+    if err := h.WriteCSV(&buf); err != nil {
+        return err
+    }
+    return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// SaveJSON writes the history to a JSON file at path
+func (h *History) SaveJSON(path string) error {
+    var buf bytes.Buffer
+    if err := h.WriteJSON(&buf); err != nil {
+        return err
+    }
+    return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+func formatFloat(value float64) string {
+    return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func formatFloat32(value float32) string {
+    return strconv.FormatFloat(float64(value), 'g', -1, 32)
+}
@@ -0,0 +1,52 @@
+package dnn
+
+import (
+    "bytes"
+    "encoding/json"
+    "math"
+    "testing"
+)
+
+func TestHistoryJSONNonFinite(t *testing.T) {
+    h := &History{
+        Validated: true,
+        Steps: []Step{
+            {Epoch: 0, Loss: 0.5, Accuracy: 0.75, R2: 0.25, ValidationLoss: 0.625},
+            {Epoch: 1, Loss: float32(math.NaN()), R2: math.Inf(-1), ValidationLoss: float32(math.Inf(1))},
+        },
+    }
+    var buf bytes.Buffer
+    if err := h.WriteJSON(&buf); err != nil {
+        t.Fatal(err)
+    }
+
+    var decoded struct {
+        Epochs []struct {
+            Loss           *float64 `json:"loss"`
+            R2             *float64 `json:"r2"`
+            ValidationLoss *float64 `json:"validation_loss"`
+        } `json:"epochs"`
+    }
+    if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+        t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+    }
+    first, diverged := decoded.Epochs[0], decoded.Epochs[1]
+    if first.Loss == nil || *first.Loss != 0.5 || first.ValidationLoss == nil || *first.ValidationLoss != 0.625 {
+        t.Errorf("finite metrics not kept: %s", buf.String())
+    }
+    if diverged.Loss != nil || diverged.R2 != nil || diverged.ValidationLoss != nil {
+        t.Errorf("non-finite metrics not written as null: %s", buf.String())
+    }
+
+    buf.Reset()
+    if err := h.WriteCSV(&buf); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestMeanLossEmpty(t *testing.T) {
+    n := trainedNetwork(t)
+    if loss := n.meanLoss(NewMatrix(0, 2), NewMatrix(0, 2)); loss != 0 {
+        t.Errorf("meanLoss without rows = %v, want 0", loss)
+    }
+}
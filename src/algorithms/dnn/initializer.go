@@ -0,0 +1,158 @@
+package dnn
+
+import (
+    "math"
+    "math/rand"
+)
+
+// Initializer fills a weight matrix with initial values drawn from rng. Each
+// row holds the weights of one output unit, so the fan-in of the layer is the
+// number of columns and the fan-out the number of rows. Biases are
+// initialized as a single row.
+type Initializer interface {
+    Initialize(weights *Matrix, rng *rand.Rand)
+}
+
+// Zeros sets every value to zero.
+type Zeros struct{}
+
+func (Zeros) Initialize(weights *Matrix, rng *rand.Rand) {
+    weights.Zero()
+}
+
+// Constant sets every value to Value.
+type Constant struct {
+    Value float32
+}
+
+func (c Constant) Initialize(weights *Matrix, rng *rand.Rand) {
+    for i := range weights.Data {
+        weights.Data[i] = c.Value
+    }
+}
+
+// Uniform draws values uniformly from [Low, High).
+type Uniform struct {
+    Low  float32
+    High float32
+}
+
+func (u Uniform) Initialize(weights *Matrix, rng *rand.Rand) {
+    fillUniform(weights, rng, float64(u.Low), float64(u.High))
+}
+
+// LeCunNormal draws values from a normal distribution with standard deviation
+// sqrt(1/fanIn), the historical default of Dense layers.
+type LeCunNormal struct{}
+
+func (LeCunNormal) Initialize(weights *Matrix, rng *rand.Rand) {
+    fillNormal(weights, rng, math.Sqrt(1/float64(weights.Cols)))
+}
+
+// XavierUniform, also known as Glorot uniform, draws values uniformly from
+// ±sqrt(6/(fanIn+fanOut)), which keeps the variance of activations and
+// gradients balanced through sigmoid and tanh layers.
+type XavierUniform struct{}
+
+func (XavierUniform) Initialize(weights *Matrix, rng *rand.Rand) {
+    limit := math.Sqrt(6 / float64(weights.Rows+weights.Cols))
+    fillUniform(weights, rng, -limit, limit)
+}
+
+// XavierNormal, also known as Glorot normal, draws values from a normal
+// distribution with standard deviation sqrt(2/(fanIn+fanOut)).
+type XavierNormal struct{}
+
+func (XavierNormal) Initialize(weights *Matrix, rng *rand.Rand) {
+    fillNormal(weights, rng, math.Sqrt(2/float64(weights.Rows+weights.Cols)))
+}
+
+// HeUniform, also known as Kaiming uniform, draws values uniformly from
+// ±sqrt(6/fanIn), compensating for ReLU zeroing half of its inputs.
+type HeUniform struct{}
+
+func (HeUniform) Initialize(weights *Matrix, rng *rand.Rand) {
+    limit := math.Sqrt(6 / float64(weights.Cols))
+    fillUniform(weights, rng, -limit, limit)
+}
+
+// HeNormal, also known as Kaiming normal, draws values from a normal
+// distribution with standard deviation sqrt(2/fanIn).
+type HeNormal struct{}
+
+func (HeNormal) Initialize(weights *Matrix, rng *rand.Rand) {
+    fillNormal(weights, rng, math.Sqrt(2/float64(weights.Cols)))
+}
+
+// Orthogonal fills the weights with a random orthogonal matrix scaled by
+// Gain, which defaults to 1. The rows are orthonormal when there are no more
+// rows than columns, and the columns otherwise. It helps deep stacks keep
+// the norm of signals that pass through them.
+type Orthogonal struct {
+    Gain float32
+}
+
+func (o Orthogonal) Initialize(weights *Matrix, rng *rand.Rand) {
+    rows, cols := weights.Rows, weights.Cols
+    transposed := rows > cols
+    if transposed {
+        rows, cols = cols, rows
+    }
+
+    // Gram-Schmidt over rows of a Gaussian matrix, redrawing the rare row
+    // that is nearly dependent on the previous ones
+    basis := make([][]float64, rows)
+    for i := range basis {
+        for {
+            row := make([]float64, cols)
+            for k := range row {
+                row[k] = rng.NormFloat64()
+            }
+            for _, prev := range basis[:i] {
+                var dot float64
+                for k := range row {
+                    dot += row[k] * prev[k]
+                }
+                for k := range row {
+                    row[k] -= dot * prev[k]
+                }
+            }
+            var norm float64
+            for _, v := range row {
+                norm += v * v
+            }
+            norm = math.Sqrt(norm)
+            if norm < 1e-6 {
+                continue
+            }
+            for k := range row {
+                row[k] /= norm
+            }
+            basis[i] = row
+            break
+        }
+    }
+
+    gain := float64(orDefault(o.Gain, 1))
+    for i := range basis {
+        for k, v := range basis[i] {
+            if transposed {
+                weights.Set(k, i, float32(gain*v))
+            } else {
+                weights.Set(i, k, float32(gain*v))
+            }
+        }
+    }
+}
+
+func fillUniform(weights *Matrix, rng *rand.Rand, low, high float64) {
+    for i := range weights.Data {
+        weights.Data[i] = float32(low + (high-low)*rng.Float64())
+    }
+}
+
+func fillNormal(weights *Matrix, rng *rand.Rand, std float64) {
+    for i := range weights.Data {
+        weights.Data[i] = float32(rng.NormFloat64() * std)
+    }
+}
@@ -0,0 +1,228 @@
+package dnn
+
+import "math/rand"
+
+// Layer is a building block of a Network. Initialize is called once the
+// width of the layer's inputs is known, and returns the width of its
+// outputs. Forward maps a batch of input rows to a batch of output rows; in
+// training mode the layer keeps whatever it needs for Backward, which takes
+// the gradient of the loss with respect to each output row, fills the
+// gradients returned by Grads and returns the gradient with respect to each
+// input row. The matrices returned by Forward and Backward are buffers owned
+// by the layer, reused across batches and only valid until the next call.
+// Params and Grads are aligned, and States holds the optimizer state of each
+// parameter vector, owned by the layer. The strategy decides how the layer
+// spreads its work across goroutines; layers bind the functions they hand to
+// it once, during Initialize, so that a training step does not allocate.
+type Layer interface {
+    Initialize(inputWidth int, strategy Strategy) int
+    Forward(inputs *Matrix, training bool) *Matrix
+    Backward(grads *Matrix) *Matrix
+    Params() []Vector
+    Grads() []Vector
+    States() []ParamState
+}
+
+// randomized is implemented by layers that draw random values, such as
+// initial weights or dropout masks, so that the network can hand them its
+// seeded source before Initialize.
+type randomized interface {
+    setRand(rng *rand.Rand)
+}
+
+// resettable is implemented by layers whose parameters survive repeated
+// calls to Initialize, so that Network.Reset can have them drawn again.
+type resettable interface {
+    reset()
+}
+
+// Penalized is implemented by layers that add a penalty term to the loss,
+// such as weight decay on a Dense layer.
+type Penalized interface {
+    Penalty() float32
+}
+
+// Dense is a fully connected feed-forward layer followed by its Activation,
+// which defaults to SigmoidActivation. L1 and L2 add weight penalties to the
+// loss. WeightInit and BiasInit choose how the weights and biases are drawn,
+// and default to LeCunNormal and Uniform{0, 1}.
+type Dense struct {
+    Name       string
+    Width      int
+    Activation Activation
+    L1         float32
+    L2         float32
+    WeightInit Initializer
+    BiasInit   Initializer
+
+    strategy    Strategy
+    rng         *rand.Rand
+    initialized bool
+    fused       bool
+    weights     *Matrix
+    biases      Vector
+    gradW       *Matrix
+    gradB       Vector
+    params      []Vector
+    grads       []Vector
+    states      []ParamState
+    lastInputs  *Matrix
+    lastGrads   *Matrix
+    z           Matrix
+    outputs     Matrix
+    delta       Matrix
+    gradInputs  Matrix
+    forwardMul  gemm
+    weightMul   gemm
+    inputMul    gemm
+    activate    func(start, end int)
+    derive      func(start, end int)
+    average     func(start, end int)
+}
+
+// Initialize sets up the needed data structures and random initial values
+// for the layer. If key values are unspecified, defaults are configured.
+// Weights already in place are kept as long as the input width is unchanged,
+// which lets training resume where it stopped; call Network.Reset to draw
+// them again.
+func (l *Dense) Initialize(inputWidth int, strategy Strategy) int {
+    l.strategy = strategy
+    l.activate = l.activateRows
+    l.derive = l.deriveRows
+    l.average = l.averageGradRows
+    if l.initialized && l.weights.Cols == inputWidth {
+        return l.Width
+    }
+
+    if l.Activation == nil {
+        l.Activation = SigmoidActivation{}
+    }
+    if l.WeightInit == nil {
+        l.WeightInit = LeCunNormal{}
+    }
+    if l.BiasInit == nil {
+        l.BiasInit = Uniform{Low: 0, High: 1}
+    }
+    rng := l.rng
+    if rng == nil {
+        rng = rand.New(rand.NewSource(rand.Int63()))
+    }
+
+    l.weights = NewMatrix(l.Width, inputWidth)
+    l.WeightInit.Initialize(l.weights, rng)
+    l.biases = make(Vector, l.Width)
+    l.BiasInit.Initialize(&Matrix{Rows: 1, Cols: l.Width, Data: l.biases}, rng)
+    l.gradW = NewMatrix(l.Width, inputWidth)
+    l.gradB = make(Vector, l.Width)
+
+    l.params = make([]Vector, 0, l.Width+1)
+    l.grads = make([]Vector, 0, l.Width+1)
+    for i := 0; i < l.Width; i++ {
+        l.params = append(l.params, l.weights.Row(i))
+        l.grads = append(l.grads, l.gradW.Row(i))
+    }
+    l.params = append(l.params, l.biases)
+    l.grads = append(l.grads, l.gradB)
+    l.states = make([]ParamState, l.Width+1)
+    for i := 0; i < l.Width; i++ {
+        l.states[i].Decay = true
+    }
+
+    l.initialized = true
+    return l.Width
+}
+
+func (l *Dense) setRand(rng *rand.Rand) { l.rng = rng }
+
+func (l *Dense) reset() { l.initialized = false }
+
+// Forward computes the activations of the whole batch as the matrix product
+// of the inputs with the transposed weights, followed by the biases and the
+// activation of every row.
+func (l *Dense) Forward(inputs *Matrix, training bool) *Matrix {
+    l.forwardMul.mulTransB(&l.z, inputs, l.weights, l.strategy)
+    l.outputs.Resize(inputs.Rows, l.Width)
+    l.strategy.Run(inputs.Rows, l.activate)
+    if training {
+        l.lastInputs = inputs
+    }
+    return &l.outputs
+}
+
+// activateRows adds the biases to rows [start, end) of the pre-activations
+// and applies the activation
+func (l *Dense) activateRows(start, end int) {
+    for b := start; b < end; b++ {
+        z := l.z.Row(b)
+        for i := range z {
+            z[i] += l.biases[i]
+        }
+        l.Activation.Forward(l.outputs.Row(b), z)
+    }
+}
+
+// Backward computes the gradients of the weights and biases averaged over
+// the batch, including the weight penalty, and returns the gradient with
+// respect to the layer's inputs. Both are expressed as matrix products of
+// the per-row deltas.
+func (l *Dense) Backward(grads *Matrix) *Matrix {
+    l.lastGrads = grads
+    l.delta.Resize(grads.Rows, l.Width)
+    l.strategy.Run(grads.Rows, l.derive)
+
+    l.weightMul.mulTransA(l.gradW, &l.delta, l.lastInputs, l.strategy)
+    l.strategy.Run(l.Width, l.average)
+    l.delta.SumRows(l.gradB)
+    for j := range l.gradB {
+        l.gradB[j] /= float32(grads.Rows)
+    }
+
+    l.inputMul.mul(&l.gradInputs, &l.delta, l.weights, l.strategy)
+    return &l.gradInputs
+}
+
+// deriveRows applies the activation Jacobian to rows [start, end) of the
+// incoming gradients, giving the per-row deltas. An output layer fused with
+// its loss already receives them.
+func (l *Dense) deriveRows(start, end int) {
+    for b := start; b < end; b++ {
+        if l.fused {
+            copy(l.delta.Row(b), l.lastGrads.Row(b))
+            continue
+        }
+        l.Activation.Backward(l.delta.Row(b), l.z.Row(b), l.outputs.Row(b), l.lastGrads.Row(b))
+    }
+}
+
+// averageGradRows averages weight gradient rows [start, end) over the batch
+// and adds the weight penalty
+func (l *Dense) averageGradRows(start, end int) {
+    batch := float32(l.delta.Rows)
+    for j := start; j < end; j++ {
+        row := l.gradW.Row(j)
+        for k := range row {
+            row[k] /= batch
+        }
+        addWeightPenaltyGrad(row, l.weights.Row(j), l.L1, l.L2)
+    }
+}
+
+// Params returns every weight row followed by the biases
+func (l *Dense) Params() []Vector {
+    return l.params
+}
+
+// Grads returns the gradients aligned with Params
+func (l *Dense) Grads() []Vector {
+    return l.grads
+}
+
+// States returns the optimizer state aligned with Params
+func (l *Dense) States() []ParamState {
+    return l.states
+}
+
+// Penalty returns the L1/L2 penalty of the layer's weights
+func (l *Dense) Penalty() float32 {
+    return weightPenalty(l.params[:l.Width], l.L1, l.L2)
+}
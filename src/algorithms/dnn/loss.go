@@ -0,0 +1,189 @@
+package dnn
+
+import "math"
+
+// Loss is a training objective. Loss returns the loss of a single prediction
+// row against its label row, and Gradient writes the gradient of that loss
+// with respect to the prediction into dst, which is what the output layer
+// propagates backwards unless the loss is fused with its activation (see
+// fusedLoss). Reported losses are averaged over rows.
+type Loss interface {
+    Loss(prediction, label Vector) float32
+    Gradient(dst, prediction, label Vector)
+}
+
+// lossEpsilon keeps logarithms and divisions finite for saturated outputs.
+const lossEpsilon = 1e-7
+
+// MeanSquaredError sums the squared differences of every output, matching
+// the loss the network has always reported.
+type MeanSquaredError struct{}
+
+func (MeanSquaredError) Loss(prediction, label Vector) float32 {
+    var loss float32
+    for i := range prediction {
+        diff := prediction[i] - label[i]
+        loss += diff * diff
+    }
+    return loss
+}
+
+func (MeanSquaredError) Gradient(dst, prediction, label Vector) {
+    for i := range prediction {
+        dst[i] = 2 * (prediction[i] - label[i])
+    }
+}
+
+// MeanAbsoluteError sums the absolute differences of every output.
+type MeanAbsoluteError struct{}
+
+func (MeanAbsoluteError) Loss(prediction, label Vector) float32 {
+    var loss float32
+    for i := range prediction {
+        loss += float32(math.Abs(float64(prediction[i] - label[i])))
+    }
+    return loss
+}
+
+func (MeanAbsoluteError) Gradient(dst, prediction, label Vector) {
+    for i := range prediction {
+        switch diff := prediction[i] - label[i]; {
+        case diff > 0:
+            dst[i] = 1
+        case diff < 0:
+            dst[i] = -1
+        default:
+            dst[i] = 0
+        }
+    }
+}
+
+// Huber is quadratic for differences up to Delta and linear beyond it, which
+// makes it robust to outliers. Delta defaults to 1 when left at zero.
+type Huber struct {
+    Delta float32
+}
+
+func (h Huber) delta() float32 {
+    if h.Delta == 0 {
+        return 1
+    }
+    return h.Delta
+}
+
+func (h Huber) Loss(prediction, label Vector) float32 {
+    delta := h.delta()
+    var loss float32
+    for i := range prediction {
+        diff := float32(math.Abs(float64(prediction[i] - label[i])))
+        if diff <= delta {
+            loss += 0.5 * diff * diff
+        } else {
+            loss += delta * (diff - 0.5*delta)
+        }
+    }
+    return loss
+}
+
+func (h Huber) Gradient(dst, prediction, label Vector) {
+    delta := h.delta()
+    for i := range prediction {
+        diff := prediction[i] - label[i]
+        switch {
+        case diff > delta:
+            dst[i] = delta
+        case diff < -delta:
+            dst[i] = -delta
+        default:
+            dst[i] = diff
+        }
+    }
+}
+
+// fusedLoss is implemented by losses whose gradient through a matching
+// output activation has a closed form. When the last layer is a Dense with
+// such an activation, the network writes gradientZ, the gradient with respect
+// to the pre-activations, and the layer skips the activation Jacobian. This
+// keeps the gradient of a confidently wrong output at prediction - label,
+// where the chained form underflows to zero once the activation saturates in
+// float32.
+type fusedLoss interface {
+    fusesWith(activation Activation) bool
+    gradientZ(dst, prediction, label Vector)
+}
+
+// BinaryCrossEntropy is the log loss of independent binary targets. It
+// expects outputs in (0, 1), typically from a SigmoidActivation layer, with
+// which it is fused so that the output delta is prediction - label.
+type BinaryCrossEntropy struct{}
+
+func (BinaryCrossEntropy) Loss(prediction, label Vector) float32 {
+    var loss float64
+    for i := range prediction {
+        p := clampProbability(prediction[i])
+        y := float64(label[i])
+        loss -= y*math.Log(p) + (1-y)*math.Log(1-p)
+    }
+    return float32(loss)
+}
+
+func (BinaryCrossEntropy) Gradient(dst, prediction, label Vector) {
+    for i := range prediction {
+        p := clampProbability(prediction[i])
+        dst[i] = float32((p - float64(label[i])) / (p * (1 - p)))
+    }
+}
+
+func (BinaryCrossEntropy) fusesWith(activation Activation) bool {
+    _, ok := activation.(SigmoidActivation)
+    return ok
+}
+
+func (BinaryCrossEntropy) gradientZ(dst, prediction, label Vector) {
+    for i := range prediction {
+        dst[i] = prediction[i] - label[i]
+    }
+}
+
+// CategoricalCrossEntropy is the log loss of a one-hot encoded multiclass
+// target. It expects a probability distribution over the outputs, typically
+// from a Softmax layer, with which it is fused so that the output delta is
+// prediction - label.
+type CategoricalCrossEntropy struct{}
+
+func (CategoricalCrossEntropy) Loss(prediction, label Vector) float32 {
+    var loss float64
+    for i := range prediction {
+        if label[i] != 0 {
+            loss -= float64(label[i]) * math.Log(clampProbability(prediction[i]))
+        }
+    }
+    return float32(loss)
+}
+
+func (CategoricalCrossEntropy) Gradient(dst, prediction, label Vector) {
+    for i := range prediction {
+        dst[i] = float32(-float64(label[i]) / clampProbability(prediction[i]))
+    }
+}
+
+func (CategoricalCrossEntropy) fusesWith(activation Activation) bool {
+    _, ok := activation.(Softmax)
+    return ok
+}
+
+// gradientZ scales the prediction by the label mass, which is 1 for one-hot
+// labels
+func (CategoricalCrossEntropy) gradientZ(dst, prediction, label Vector) {
+    var mass float32
+    for _, y := range label {
+        mass += y
+    }
+    for i := range prediction {
+        dst[i] = mass*prediction[i] - label[i]
+    }
+}
+
+func clampProbability(p float32) float64 {
+    return math.Min(math.Max(float64(p), lossEpsilon), 1-lossEpsilon)
+}
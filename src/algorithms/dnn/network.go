@@ -0,0 +1,505 @@
+package dnn
+
+import (
+    "errors"
+    "fmt"
+    "math/rand"
+    "sync"
+    "time"
+)
+
+// Strategy decides how work over a range of independent items is spread
+// across goroutines. Run must call fn over disjoint sub-ranges covering
+// [0, n) and return once all of them have completed. Every layer of a Network
+// routes its work through the network's Strategy, so that switching between
+// Sequential, Sharded and Pool changes only how the identical math is
+// executed.
+type Strategy interface {
+    Run(n int, fn func(start, end int))
+}
+
+// Network is a feed-forward neural network made of a stack of layers over
+// inputs of width InputWidth. Training runs in mini-batches of BatchSize rows
+// (one row at a time if unset), minimizing Loss with Optimizer, at a learning
+// rate taken from Schedule when set. Strategy selects the execution strategy
+// and defaults to Sequential. A classifier ends in a sigmoid or softmax layer
+// trained with a cross-entropy loss, while a regressor ends in a Linear layer
+// trained with MeanSquaredError or Huber and is evaluated with
+// EvaluateRegression. When Seed is non-zero, initial weights are drawn from a
+// source seeded with it, so that networks with the same Seed and layers start
+// from the same weights. When ValidationInputs and ValidationLabels are set,
+// every epoch is also evaluated on them, which EarlyStopping requires.
+type Network struct {
+    InputWidth    int
+    Layers        []Layer
+    LearningRate  float32
+    BatchSize     int
+    Loss          Loss
+    Optimizer     Optimizer
+    Schedule      Schedule
+    EarlyStopping *EarlyStopping
+    Strategy      Strategy
+    Seed          int64
+    Introspect    func(step Step)
+
+    ValidationInputs Frame
+    ValidationLabels Frame
+
+    initialized bool
+    rate        float32
+    outputWidth int
+    batchX      Matrix
+    batchY      Matrix
+    grads       Matrix
+    outputs     *Matrix
+    labels      *Matrix
+    fused       fusedLoss
+    updating    Layer
+    lossGrad    func(start, end int)
+    update      func(start, end int)
+}
+
+// Step captures status updates that happens within a single Epoch, for use in
+// introspecting models and in the History returned by Train. Accuracy is
+// meaningful for classifiers and R2 for regressors. The validation fields are
+// set only when the network has a validation set. Duration is the wall time of the epoch,
+// including validation, and SamplesPerSecond the training throughput.
+type Step struct {
+    Epoch              int
+    Loss               float32
+    Accuracy           float64
+    R2                 float64
+    LearningRate       float32
+    ValidationLoss     float32
+    ValidationAccuracy float64
+    ValidationR2       float64
+    Duration           time.Duration
+    SamplesPerSecond   float64
+}
+
+// Initialize sets up network layers with the needed memory allocations and
+// references for proper operation. It is called automatically during
+// training and before the first prediction, provided separately only to facilitate more precise use of the
+// network from a performance analysis perspective.
+func (n *Network) Initialize() {
+    if n.Loss == nil {
+        n.Loss = MeanSquaredError{}
+    }
+    if n.Optimizer == nil {
+        n.Optimizer = SGD{}
+    }
+    if n.Strategy == nil {
+        n.Strategy = Sequential{}
+    }
+    n.lossGrad = n.lossGradRows
+    n.update = n.updateParams
+    seed := n.Seed
+    if seed == 0 {
+        seed = rand.Int63()
+    }
+    rng := rand.New(rand.NewSource(seed))
+    width := n.InputWidth
+    for _, layer := range n.Layers {
+        if r, ok := layer.(randomized); ok {
+            r.setRand(rng)
+        }
+        if d, ok := layer.(*Dense); ok {
+            d.fused = false
+        }
+        width = layer.Initialize(width, n.Strategy)
+    }
+    n.outputWidth = width
+
+    // Fuse a cross-entropy loss with the matching output activation
+    n.fused = nil
+    if len(n.Layers) > 0 {
+        out, isDense := n.Layers[len(n.Layers)-1].(*Dense)
+        loss, isFused := n.Loss.(fusedLoss)
+        if isDense && isFused && loss.fusesWith(out.Activation) {
+            out.fused = true
+            n.fused = loss
+        }
+    }
+    n.initialized = true
+}
+
+// Reset discards the trained parameters of every layer, so that the next
+// Initialize or Train draws them again from the layers' initializers
+func (n *Network) Reset() {
+    n.initialized = false
+    for _, layer := range n.Layers {
+        if r, ok := layer.(resettable); ok {
+            r.reset()
+        }
+    }
+}
+
+// Weights returns a copy of the parameters of every layer, followed by its
+// running statistics for layers such as BatchNorm, in the layout expected by
+// SetWeights
+func (n *Network) Weights() []Frame {
+    weights := make([]Frame, len(n.Layers))
+    for i, layer := range n.Layers {
+        weights[i] = cloneFrame(layerState(layer))
+    }
+    return weights
+}
+
+// SetWeights initializes the network and overwrites the parameters of every
+// layer with weights, as returned by Weights, for instance to warm start
+// training from a previous run. Later calls to Train continue from these
+// weights.
+func (n *Network) SetWeights(weights []Frame) error {
+    if len(weights) != len(n.Layers) {
+        return fmt.Errorf("weights for %d layers given to a network of %d layers", len(weights), len(n.Layers))
+    }
+    n.Initialize()
+    states := make([]Frame, len(n.Layers))
+    for i, layer := range n.Layers {
+        states[i] = layerState(layer)
+        if len(states[i]) != len(weights[i]) {
+            return fmt.Errorf("layer %d has %d parameter vectors, got %d", i, len(states[i]), len(weights[i]))
+        }
+        for j := range states[i] {
+            if len(states[i][j]) != len(weights[i][j]) {
+                return fmt.Errorf("layer %d vector %d has width %d, got %d", i, j, len(states[i][j]), len(weights[i][j]))
+            }
+        }
+    }
+    for i := range states {
+        copyFrame(states[i], weights[i])
+    }
+    return nil
+}
+
+// layerState returns the parameter vectors of a layer followed by its running
+// statistics, sharing the layer's storage
+func layerState(layer Layer) Frame {
+    state := Frame(layer.Params())
+    if r, ok := layer.(runningStatsLayer); ok {
+        state = append(state[:len(state):len(state)], r.runningStats()...)
+    }
+    return state
+}
+
+// Train takes in a set of inputs and a set of labels and trains the network
+// using backpropagation to adjust internal weights to minimize loss, over the
+// specified number of epochs. The frames are copied once into contiguous
+// matrices, and every mini-batch is a view over them that is propagated
+// forwards and backwards through all layers, as matrix products, before a
+// single parameter update. Layers reuse their buffers between batches, so no
+// per-batch data is allocated. The learning rate of every epoch is taken from
+// the Schedule, if any. Every epoch is evaluated on the validation set, if
+// any, and training ends early when EarlyStopping is set and the validation
+// loss stops improving, restoring the best weights seen. The
+// History of every epoch is returned after training completes; its Loss is
+// the final loss value, including any weight penalties.
+func (n *Network) Train(epochs int, inputs, labels Frame) (*History, error) {
+    if err := n.check(inputs, labels); err != nil {
+        return nil, err
+    }
+    return n.TrainMatrix(epochs, FromFrame(inputs), FromFrame(labels))
+}
+
+// TrainMatrix is Train over inputs and labels already held in matrices
+func (n *Network) TrainMatrix(epochs int, inputs, labels *Matrix) (*History, error) {
+    if err := n.checkMatrix(inputs, labels); err != nil {
+        return nil, err
+    }
+
+    n.Initialize()
+    if err := n.checkOutputs(labels); err != nil {
+        return nil, err
+    }
+
+    batchSize := n.BatchSize
+    if batchSize <= 0 {
+        batchSize = 1
+    }
+
+    var valInputs, valLabels *Matrix
+    if len(n.ValidationInputs) > 0 {
+        valInputs, valLabels = FromFrame(n.ValidationInputs), FromFrame(n.ValidationLabels)
+        err := n.checkMatrix(valInputs, valLabels)
+        if err == nil {
+            err = n.checkOutputs(valLabels)
+        }
+        if err != nil {
+            return nil, fmt.Errorf("validation set: %w", err)
+        }
+    }
+
+    es := n.EarlyStopping
+    var best []layerSnapshot
+    if es != nil {
+        if valInputs == nil {
+            return nil, errors.New("early stopping requires a validation set")
+        }
+        es.reset()
+    }
+
+    history := &History{Steps: make([]Step, 0, epochs), Validated: valInputs != nil, BestEpoch: -1}
+    began := time.Now()
+    predictions := NewMatrix(inputs.Rows, n.outputWidth)
+    for e := 0; e < epochs; e++ {
+        started := time.Now()
+        n.rate = n.LearningRate
+        if n.Schedule != nil {
+            n.rate = n.Schedule.Rate(e, n.LearningRate)
+        }
+
+        for start := 0; start < inputs.Rows; start += batchSize {
+            end := min(start+batchSize, inputs.Rows)
+            n.batchX.View(inputs, start, end)
+            n.batchY.View(labels, start, end)
+            outputs := n.step(&n.batchX, &n.batchY)
+            copy(predictions.Data[start*n.outputWidth:end*n.outputWidth], outputs.Data)
+        }
+
+        trained := time.Since(started)
+        step := Step{
+            Epoch:        e,
+            Loss:         n.meanLoss(predictions, labels) + n.penalty(),
+            Accuracy:     labelAccuracy(predictions, labels),
+            R2:           rSquared(predictions, labels),
+            LearningRate: n.rate,
+        }
+        if trained > 0 {
+            step.SamplesPerSecond = float64(inputs.Rows) / trained.Seconds()
+        }
+
+        if valInputs != nil {
+            valPredictions := n.PredictMatrix(valInputs)
+            step.ValidationLoss = n.meanLoss(valPredictions, valLabels)
+            step.ValidationAccuracy = labelAccuracy(valPredictions, valLabels)
+            step.ValidationR2 = rSquared(valPredictions, valLabels)
+        }
+
+        stop := false
+        if es != nil {
+            var improved bool
+            improved, stop = es.observe(step.ValidationLoss)
+            if improved {
+                best = n.snapshot(best)
+                history.BestEpoch = e
+            }
+        } else {
+            history.BestEpoch = e
+        }
+        step.Duration = time.Since(started)
+        history.Steps = append(history.Steps, step)
+
+        if n.Introspect != nil {
+            n.Introspect(step)
+        }
+        if stop {
+            history.Stopped = e < epochs-1
+            break
+        }
+    }
+
+    if best != nil {
+        n.restore(best)
+    }
+
+    history.Duration = time.Since(began)
+    return history, nil
+}
+
+// step runs one mini-batch forwards and backwards through the network and
+// applies the optimizer to every layer, returning the batch predictions
+func (n *Network) step(inputs, labels *Matrix) *Matrix {
+    activations := n.backprop(inputs, labels)
+    for _, layer := range n.Layers {
+        n.updating = layer
+        n.Strategy.Run(len(layer.Params()), n.update)
+    }
+    return activations
+}
+
+// backprop runs one mini-batch forwards and backwards through the network,
+// leaving the gradients of the loss in every layer without applying them, and
+// returns the batch predictions
+func (n *Network) backprop(inputs, labels *Matrix) *Matrix {
+    activations := inputs
+    for _, layer := range n.Layers {
+        activations = layer.Forward(activations, true)
+    }
+
+    n.outputs, n.labels = activations, labels
+    n.grads.Resize(activations.Rows, activations.Cols)
+    n.Strategy.Run(activations.Rows, n.lossGrad)
+    grads := &n.grads
+    for l := len(n.Layers) - 1; l >= 0; l-- {
+        grads = n.Layers[l].Backward(grads)
+    }
+    return activations
+}
+
+// lossGradRows writes the loss gradient of output rows [start, end) of the
+// current batch, with respect to the output pre-activations when the loss is
+// fused with the output layer
+func (n *Network) lossGradRows(start, end int) {
+    for b := start; b < end; b++ {
+        if n.fused != nil {
+            n.fused.gradientZ(n.grads.Row(b), n.outputs.Row(b), n.labels.Row(b))
+        } else {
+            n.Loss.Gradient(n.grads.Row(b), n.outputs.Row(b), n.labels.Row(b))
+        }
+    }
+}
+
+// updateParams applies the optimizer to parameters [start, end) of the layer
+// being updated
+func (n *Network) updateParams(start, end int) {
+    params, grads, states := n.updating.Params(), n.updating.Grads(), n.updating.States()
+    for i := start; i < end; i++ {
+        n.Optimizer.Update(params[i], grads[i], &states[i], n.rate)
+    }
+}
+
+// Predict takes in a set of input rows with the width of the input layer,
+// and returns a frame of prediction rows with the width of the output layer,
+// representing the predictions of the network. Layers run in inference mode,
+// so Dropout is disabled and BatchNorm uses its running statistics. A network
+// that was never trained is initialized first, so its predictions come from
+// the initial weights. An empty frame gives an empty result.
+func (n *Network) Predict(inputs Frame) Frame {
+    return n.PredictMatrix(fromFrameWidth(inputs, n.InputWidth)).ToFrame()
+}
+
+// PredictMatrix is Predict over inputs held in a matrix. The returned matrix
+// is a copy owned by the caller.
+func (n *Network) PredictMatrix(inputs *Matrix) *Matrix {
+    if !n.initialized {
+        n.Initialize()
+    }
+    if inputs.Rows == 0 {
+        return NewMatrix(0, n.outputWidth)
+    }
+    activations := inputs
+    for _, layer := range n.Layers {
+        activations = layer.Forward(activations, false)
+    }
+    result := &Matrix{}
+    result.CopyFrom(activations)
+    return result
+}
+
+// meanLoss returns the loss averaged over the prediction rows, accumulating
+// partial sums from every range of the strategy, or zero without rows
+func (n *Network) meanLoss(predictions, labels *Matrix) float32 {
+    if predictions.Rows != labels.Rows {
+        panic("matrices must have the same number of rows")
+    }
+    if predictions.Rows == 0 {
+        return 0
+    }
+    var mu sync.Mutex
+    var loss float32
+    n.Strategy.Run(predictions.Rows, func(start, end int) {
+        var localLoss float32
+        for i := start; i < end; i++ {
+            localLoss += n.Loss.Loss(predictions.Row(i), labels.Row(i))
+        }
+        mu.Lock()
+        loss += localLoss
+        mu.Unlock()
+    })
+    return loss / float32(predictions.Rows)
+}
+
+// penalty returns the total weight penalty of the network
+func (n *Network) penalty() float32 {
+    var penalty float32
+    for _, layer := range n.Layers {
+        if p, ok := layer.(Penalized); ok {
+            penalty += p.Penalty()
+        }
+    }
+    return penalty
+}
+
+// snapshot copies the parameters and running statistics of every layer into
+// dst, allocating it on first use
+func (n *Network) snapshot(dst []layerSnapshot) []layerSnapshot {
+    if dst == nil {
+        dst = make([]layerSnapshot, len(n.Layers))
+        for i, layer := range n.Layers {
+            dst[i].params = cloneFrame(layer.Params())
+            if r, ok := layer.(runningStatsLayer); ok {
+                dst[i].running = cloneFrame(r.runningStats())
+            }
+        }
+        return dst
+    }
+    for i, layer := range n.Layers {
+        copyFrame(dst[i].params, layer.Params())
+        if r, ok := layer.(runningStatsLayer); ok {
+            copyFrame(dst[i].running, r.runningStats())
+        }
+    }
+    return dst
+}
+
+// restore writes previously snapshotted parameters back into the layers
+func (n *Network) restore(src []layerSnapshot) {
+    for i, layer := range n.Layers {
+        copyFrame(layer.Params(), src[i].params)
+        if r, ok := layer.(runningStatsLayer); ok {
+            copyFrame(r.runningStats(), src[i].running)
+        }
+    }
+}
+
+func (n *Network) check(inputs Frame, outputs Frame) error {
+    if len(n.Layers) == 0 {
+        return errors.New("ann must have at least one layer")
+    }
+
+    if len(inputs) == 0 {
+        return errors.New("inputs must have at least one row")
+    }
+    if len(inputs) != len(outputs) {
+        return fmt.Errorf(
+            "inputs count %d mismatched with outputs count %d",
+            len(inputs), len(outputs),
+        )
+    }
+    return nil
+}
+
+func (n *Network) checkMatrix(inputs, outputs *Matrix) error {
+    if len(n.Layers) == 0 {
+        return errors.New("ann must have at least one layer")
+    }
+
+    if inputs.Rows == 0 {
+        return errors.New("inputs must have at least one row")
+    }
+    if inputs.Rows != outputs.Rows {
+        return fmt.Errorf(
+            "inputs count %d mismatched with outputs count %d",
+            inputs.Rows, outputs.Rows,
+        )
+    }
+    if inputs.Cols != n.InputWidth {
+        return fmt.Errorf(
+            "inputs width %d mismatched with network input width %d",
+            inputs.Cols, n.InputWidth,
+        )
+    }
+    return nil
+}
+
+// checkOutputs verifies the width of the labels against the output layer,
+// which is only known once the network is initialized
+func (n *Network) checkOutputs(outputs *Matrix) error {
+    if outputs.Cols != n.outputWidth {
+        return fmt.Errorf(
+            "outputs width %d mismatched with network output width %d",
+            outputs.Cols, n.outputWidth,
+        )
+    }
+    return nil
+}
@@ -0,0 +1,64 @@
+package dnn
+
+import "testing"
+
+// trainedNetwork returns a small regression network trained for a few epochs
+// over the gradient check data
+func trainedNetwork(t *testing.T) *Network {
+    t.Helper()
+    n := &Network{
+        InputWidth:   3,
+        Layers:       []Layer{&Dense{Width: 4, Activation: Tanh{}}, &Dense{Width: 2, Activation: Linear{}}},
+        LearningRate: 0.05,
+        Seed:         1,
+    }
+    x, y := gradientCheckData(16, 3, 2, MeanSquaredError{})
+    if _, err := n.Train(3, x, y); err != nil {
+        t.Fatal(err)
+    }
+    return n
+}
+
+func TestPredictEmpty(t *testing.T) {
+    n := trainedNetwork(t)
+
+    if predictions := n.Predict(Frame{}); len(predictions) != 0 {
+        t.Errorf("Predict(Frame{}) = %v, want no rows", predictions)
+    }
+    if predictions := n.Predict(nil); len(predictions) != 0 {
+        t.Errorf("Predict(nil) = %v, want no rows", predictions)
+    }
+    predictions := n.PredictMatrix(NewMatrix(0, 3))
+    if predictions.Rows != 0 || predictions.Cols != 2 {
+        t.Errorf("PredictMatrix of 0x3 = %dx%d, want 0x2", predictions.Rows, predictions.Cols)
+    }
+}
+
+func TestTrainRejectsMismatchedData(t *testing.T) {
+    x, y := gradientCheckData(8, 3, 2, MeanSquaredError{})
+    narrowInputs, _ := gradientCheckData(8, 2, 2, MeanSquaredError{})
+    _, narrowLabels := gradientCheckData(8, 3, 1, MeanSquaredError{})
+    cases := []struct {
+        name     string
+        inputs   Frame
+        labels   Frame
+        validate func(n *Network)
+    }{
+        {name: "no rows", inputs: Frame{}, labels: Frame{}},
+        {name: "row counts", inputs: x, labels: y[:4]},
+        {name: "input width", inputs: narrowInputs, labels: y},
+        {name: "label width", inputs: x, labels: narrowLabels},
+        {name: "validation label width", inputs: x, labels: y, validate: func(n *Network) {
+            n.ValidationInputs, n.ValidationLabels = x[:2], narrowLabels[:2]
+        }},
+    }
+    for _, c := range cases {
+        n := &Network{InputWidth: 3, Layers: []Layer{&Dense{Width: 2, Activation: Linear{}}}, Seed: 1}
+        if c.validate != nil {
+            c.validate(n)
+        }
+        if _, err := n.Train(1, c.inputs, c.labels); err == nil {
+            t.Errorf("%s: Train returned no error", c.name)
+        }
+    }
+}
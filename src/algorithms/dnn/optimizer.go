@@ -0,0 +1,145 @@
+package dnn
+
+import "math"
+
+// ParamState is the per-parameter state an Optimizer keeps between updates.
+// Layers own one ParamState per weight row and one for their biases, so the
+// state lives alongside the parameters it belongs to. Decay marks parameters
+// subject to weight decay, which excludes biases.
+type ParamState struct {
+    Decay  bool
+    Step   int
+    First  Vector
+    Second Vector
+}
+
+// moments lazily allocates the moment buffers for params
+func (s *ParamState) moments(size int) {
+    if len(s.First) != size {
+        s.First = make(Vector, size)
+        s.Second = make(Vector, size)
+        s.Step = 0
+    }
+}
+
+// Optimizer applies the gradients of one parameter vector to it in place,
+// using and updating the parameter's state.
+type Optimizer interface {
+    Update(params, grads Vector, state *ParamState, learningRate float32)
+}
+
+// SGD is plain stochastic gradient descent.
+type SGD struct{}
+
+func (SGD) Update(params, grads Vector, state *ParamState, learningRate float32) {
+    for i := range params {
+        params[i] -= learningRate * grads[i]
+    }
+}
+
+// Momentum is SGD with classical momentum. Beta defaults to 0.9 when left at
+// zero.
+type Momentum struct {
+    Beta float32
+}
+
+func (o Momentum) Update(params, grads Vector, state *ParamState, learningRate float32) {
+    beta := orDefault(o.Beta, 0.9)
+    state.moments(len(params))
+    for i := range params {
+        state.First[i] = beta*state.First[i] + grads[i]
+        params[i] -= learningRate * state.First[i]
+    }
+}
+
+// Nesterov is SGD with Nesterov accelerated momentum, which evaluates the
+// momentum step ahead of the current position. Beta defaults to 0.9 when left
+// at zero.
+type Nesterov struct {
+    Beta float32
+}
+
+func (o Nesterov) Update(params, grads Vector, state *ParamState, learningRate float32) {
+    beta := orDefault(o.Beta, 0.9)
+    state.moments(len(params))
+    for i := range params {
+        state.First[i] = beta*state.First[i] + grads[i]
+        params[i] -= learningRate * (grads[i] + beta*state.First[i])
+    }
+}
+
+// RMSProp scales each step by a running average of squared gradients. Rho
+// defaults to 0.9 and Epsilon to 1e-8 when left at zero.
+type RMSProp struct {
+    Rho     float32
+    Epsilon float32
+}
+
+func (o RMSProp) Update(params, grads Vector, state *ParamState, learningRate float32) {
+    rho := orDefault(o.Rho, 0.9)
+    epsilon := orDefault(o.Epsilon, 1e-8)
+    state.moments(len(params))
+    for i := range params {
+        state.Second[i] = rho*state.Second[i] + (1-rho)*grads[i]*grads[i]
+        params[i] -= learningRate * grads[i] / (sqrt32(state.Second[i]) + epsilon)
+    }
+}
+
+// Adam keeps bias-corrected running averages of the gradients and their
+// squares. Beta1 defaults to 0.9, Beta2 to 0.999 and Epsilon to 1e-8 when
+// left at zero.
+type Adam struct {
+    Beta1   float32
+    Beta2   float32
+    Epsilon float32
+}
+
+func (o Adam) Update(params, grads Vector, state *ParamState, learningRate float32) {
+    adamUpdate(params, grads, state, learningRate, o.Beta1, o.Beta2, o.Epsilon, 0)
+}
+
+// AdamW is Adam with decoupled weight decay, applied directly to the
+// parameters marked for decay rather than folded into the gradients.
+// WeightDecay defaults to 0.01 when left at zero.
+type AdamW struct {
+    Beta1       float32
+    Beta2       float32
+    Epsilon     float32
+    WeightDecay float32
+}
+
+func (o AdamW) Update(params, grads Vector, state *ParamState, learningRate float32) {
+    var decay float32
+    if state.Decay {
+        decay = orDefault(o.WeightDecay, 0.01)
+    }
+    adamUpdate(params, grads, state, learningRate, o.Beta1, o.Beta2, o.Epsilon, decay)
+}
+
+func adamUpdate(params, grads Vector, state *ParamState, learningRate, beta1, beta2, epsilon, decay float32) {
+    beta1 = orDefault(beta1, 0.9)
+    beta2 = orDefault(beta2, 0.999)
+    epsilon = orDefault(epsilon, 1e-8)
+    state.moments(len(params))
+    state.Step++
+    correction1 := 1 - float32(math.Pow(float64(beta1), float64(state.Step)))
+    correction2 := 1 - float32(math.Pow(float64(beta2), float64(state.Step)))
+    for i := range params {
+        state.First[i] = beta1*state.First[i] + (1-beta1)*grads[i]
+        state.Second[i] = beta2*state.Second[i] + (1-beta2)*grads[i]*grads[i]
+        m := state.First[i] / correction1
+        v := state.Second[i] / correction2
+        params[i] -= learningRate * (m/(sqrt32(v)+epsilon) + decay*params[i])
+    }
+}
+
+func orDefault(value, fallback float32) float32 {
+    if value == 0 {
+        return fallback
+    }
+    return value
+}
+
+func sqrt32(x float32) float32 {
+    return float32(math.Sqrt(float64(x)))
+}
@@ -0,0 +1,77 @@
+package dnn
+
+import "math"
+
+// RegressionMetrics summarizes how well real-valued predictions match their
+// targets. Errors are averaged over every output of every row; R2 is the
+// coefficient of determination averaged over the outputs.
+type RegressionMetrics struct {
+    MSE  float64
+    RMSE float64
+    MAE  float64
+    R2   float64
+}
+
+// EvaluateRegression compares the predictions of a regression network, which
+// would typically end in a Linear Dense layer trained with MeanSquaredError
+// or Huber, against the target rows
+func EvaluateRegression(predictions, targets Frame) RegressionMetrics {
+    return evaluateRegression(FromFrame(predictions), FromFrame(targets))
+}
+
+// RSquared returns the coefficient of determination of the predictions,
+// averaged over the outputs. It is 1 for a perfect fit and 0 for a model that
+// always predicts the mean of the targets.
+func RSquared(predictions, targets Frame) float64 {
+    return rSquared(FromFrame(predictions), FromFrame(targets))
+}
+
+func evaluateRegression(predictions, targets *Matrix) RegressionMetrics {
+    if predictions.Rows != targets.Rows || predictions.Cols != targets.Cols {
+        panic("matrices must have the same shape")
+    }
+    var metrics RegressionMetrics
+    if len(targets.Data) == 0 {
+        return metrics
+    }
+    for i := range targets.Data {
+        diff := float64(predictions.Data[i] - targets.Data[i])
+        metrics.MSE += diff * diff
+        metrics.MAE += math.Abs(diff)
+    }
+    metrics.MSE /= float64(len(targets.Data))
+    metrics.MAE /= float64(len(targets.Data))
+    metrics.RMSE = math.Sqrt(metrics.MSE)
+    metrics.R2 = rSquared(predictions, targets)
+    return metrics
+}
+
+func rSquared(predictions, targets *Matrix) float64 {
+    if targets.Rows == 0 || targets.Cols == 0 {
+        return 0
+    }
+    var total float64
+    for j := 0; j < targets.Cols; j++ {
+        var mean float64
+        for i := 0; i < targets.Rows; i++ {
+            mean += float64(targets.At(i, j))
+        }
+        mean /= float64(targets.Rows)
+
+        var residual, variance float64
+        for i := 0; i < targets.Rows; i++ {
+            y := float64(targets.At(i, j))
+            diff := y - float64(predictions.At(i, j))
+            residual += diff * diff
+            variance += (y - mean) * (y - mean)
+        }
+        if variance == 0 {
+            if residual == 0 {
+                total++
+            }
+            continue
+        }
+        total += 1 - residual/variance
+    }
+    return total / float64(targets.Cols)
+}
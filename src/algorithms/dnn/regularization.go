@@ -0,0 +1,284 @@
+package dnn
+
+import (
+    "math"
+    "math/rand"
+)
+
+// Dropout zeroes each input with probability Rate during training and passes
+// inputs through unchanged at inference. The surviving inputs are scaled by
+// 1/(1-Rate) so that expected activations match inference.
+type Dropout struct {
+    Name string
+    Rate float32
+
+    strategy   Strategy
+    lastInputs *Matrix
+    frozen     bool
+    rng        *rand.Rand
+    mask       Matrix
+    outputs    Matrix
+    gradInputs Matrix
+    drop       func(start, end int)
+}
+
+// Initialize keeps the width of the previous layer
+func (l *Dropout) Initialize(inputWidth int, strategy Strategy) int {
+    l.strategy = strategy
+    l.drop = l.dropRows
+    if l.rng == nil {
+        l.rng = rand.New(rand.NewSource(rand.Int63()))
+    }
+    return inputWidth
+}
+
+// Forward applies inverted dropout in training mode, recording the scaling
+// applied to each value so that Backward can reuse it. The mask is drawn in
+// order from the layer's own source, seeded from the network Seed, so that a
+// seeded run drops the same inputs under every Strategy. A frozen layer
+// reapplies the mask of the previous batch instead, which keeps its output
+// deterministic while gradients are checked.
+func (l *Dropout) Forward(inputs *Matrix, training bool) *Matrix {
+    if !training {
+        return inputs
+    }
+    l.lastInputs = inputs
+    l.outputs.Resize(inputs.Rows, inputs.Cols)
+    if !l.frozen {
+        l.mask.Resize(inputs.Rows, inputs.Cols)
+        keep := 1 - l.Rate
+        for i := range l.mask.Data {
+            l.mask.Data[i] = 0
+            if l.rng.Float32() < keep {
+                l.mask.Data[i] = 1 / keep
+            }
+        }
+    }
+    l.strategy.Run(inputs.Rows, l.drop)
+    return &l.outputs
+}
+
+// dropRows applies the mask to rows [start, end)
+func (l *Dropout) dropRows(start, end int) {
+    cols := l.lastInputs.Cols
+    for i := start * cols; i < end*cols; i++ {
+        l.outputs.Data[i] = l.lastInputs.Data[i] * l.mask.Data[i]
+    }
+}
+
+// setRand gives the layer a source of its own, drawn from the network's, so
+// that the masks do not depend on how other layers consume theirs
+func (l *Dropout) setRand(rng *rand.Rand) { l.rng = rand.New(rand.NewSource(rng.Int63())) }
+
+// Backward routes the gradient through the inputs kept in the last Forward
+func (l *Dropout) Backward(grads *Matrix) *Matrix {
+    l.gradInputs.CopyFrom(grads)
+    l.gradInputs.MulElem(&l.mask)
+    return &l.gradInputs
+}
+
+func (l *Dropout) Params() []Vector     { return nil }
+func (l *Dropout) Grads() []Vector      { return nil }
+func (l *Dropout) States() []ParamState { return nil }
+
+// BatchNorm normalizes each input to zero mean and unit variance over the
+// batch during training, then applies a learned scale and shift. Running
+// statistics are tracked with Momentum, which defaults to 0.9, and used at
+// inference. It needs a network BatchSize greater than one to be meaningful.
+// Epsilon defaults to 1e-5.
+type BatchNorm struct {
+    Name     string
+    Momentum float32
+    Epsilon  float32
+
+    strategy    Strategy
+    initialized bool
+    gamma       Vector
+    beta        Vector
+    runningMean Vector
+    runningVar  Vector
+    gradGamma   Vector
+    gradBeta    Vector
+    params      []Vector
+    grads       []Vector
+    states      []ParamState
+    invStd      Vector
+    lastInputs  *Matrix
+    lastGrads   *Matrix
+    xhat        Matrix
+    outputs     Matrix
+    gradInputs  Matrix
+    infer       func(start, end int)
+    normalize   func(start, end int)
+    derive      func(start, end int)
+}
+
+// Initialize sets the scale to one, the shift to zero and the running
+// statistics to a standard normal distribution. Like Dense, it keeps its
+// parameters across calls while the input width is unchanged.
+func (l *BatchNorm) Initialize(inputWidth int, strategy Strategy) int {
+    l.strategy = strategy
+    l.infer = l.inferRows
+    l.normalize = l.normalizeFeatures
+    l.derive = l.deriveFeatures
+    if l.initialized && len(l.gamma) == inputWidth {
+        return inputWidth
+    }
+    if l.Momentum == 0 {
+        l.Momentum = 0.9
+    }
+    if l.Epsilon == 0 {
+        l.Epsilon = 1e-5
+    }
+    l.gamma = make(Vector, inputWidth)
+    l.runningVar = make(Vector, inputWidth)
+    for i := range l.gamma {
+        l.gamma[i] = 1
+        l.runningVar[i] = 1
+    }
+    l.beta = make(Vector, inputWidth)
+    l.runningMean = make(Vector, inputWidth)
+    l.gradGamma = make(Vector, inputWidth)
+    l.gradBeta = make(Vector, inputWidth)
+    l.invStd = make(Vector, inputWidth)
+    l.params = []Vector{l.gamma, l.beta}
+    l.grads = []Vector{l.gradGamma, l.gradBeta}
+    l.states = make([]ParamState, 2)
+    l.initialized = true
+    return inputWidth
+}
+
+func (l *BatchNorm) reset() { l.initialized = false }
+
+// Forward normalizes with the batch statistics in training mode, folding
+// them into the running statistics, and with the running statistics
+// otherwise.
+func (l *BatchNorm) Forward(inputs *Matrix, training bool) *Matrix {
+    l.lastInputs = inputs
+    l.outputs.Resize(inputs.Rows, len(l.gamma))
+    if !training {
+        l.strategy.Run(inputs.Rows, l.infer)
+        return &l.outputs
+    }
+    l.xhat.Resize(inputs.Rows, len(l.gamma))
+    l.strategy.Run(len(l.gamma), l.normalize)
+    return &l.outputs
+}
+
+// inferRows normalizes rows [start, end) with the running statistics
+func (l *BatchNorm) inferRows(start, end int) {
+    for b := start; b < end; b++ {
+        in, out := l.lastInputs.Row(b), l.outputs.Row(b)
+        for i := range in {
+            xhat := (in[i] - l.runningMean[i]) / sqrt32(l.runningVar[i]+l.Epsilon)
+            out[i] = l.gamma[i]*xhat + l.beta[i]
+        }
+    }
+}
+
+// normalizeFeatures normalizes features [start, end) over the batch and
+// updates their running statistics
+func (l *BatchNorm) normalizeFeatures(start, end int) {
+    inputs, width := l.lastInputs, len(l.gamma)
+    rows := inputs.Rows
+    batch := float32(rows)
+    for i := start; i < end; i++ {
+        var mean, variance float32
+        for b := 0; b < rows; b++ {
+            mean += inputs.Data[b*width+i]
+        }
+        mean /= batch
+        for b := 0; b < rows; b++ {
+            diff := inputs.Data[b*width+i] - mean
+            variance += diff * diff
+        }
+        variance /= batch
+        l.invStd[i] = 1 / sqrt32(variance+l.Epsilon)
+        for b := 0; b < rows; b++ {
+            xhat := (inputs.Data[b*width+i] - mean) * l.invStd[i]
+            l.xhat.Data[b*width+i] = xhat
+            l.outputs.Data[b*width+i] = l.gamma[i]*xhat + l.beta[i]
+        }
+        l.runningMean[i] = l.Momentum*l.runningMean[i] + (1-l.Momentum)*mean
+        l.runningVar[i] = l.Momentum*l.runningVar[i] + (1-l.Momentum)*variance
+    }
+}
+
+// Backward computes the averaged gradients of the scale and shift and
+// returns the gradient with respect to the batch inputs, accounting for the
+// dependence of the batch statistics on every row
+func (l *BatchNorm) Backward(grads *Matrix) *Matrix {
+    l.lastGrads = grads
+    l.gradInputs.Resize(grads.Rows, len(l.gamma))
+    l.strategy.Run(len(l.gamma), l.derive)
+    return &l.gradInputs
+}
+
+// deriveFeatures computes the gradients of features [start, end)
+func (l *BatchNorm) deriveFeatures(start, end int) {
+    grads, width := l.lastGrads, len(l.gamma)
+    rows := grads.Rows
+    batch := float32(rows)
+    for i := start; i < end; i++ {
+        var sumDxhat, sumDxhatXhat, sumGradXhat, sumGrad float32
+        for b := 0; b < rows; b++ {
+            g, xhat := grads.Data[b*width+i], l.xhat.Data[b*width+i]
+            dxhat := g * l.gamma[i]
+            sumDxhat += dxhat
+            sumDxhatXhat += dxhat * xhat
+            sumGradXhat += g * xhat
+            sumGrad += g
+        }
+        for b := 0; b < rows; b++ {
+            dxhat := grads.Data[b*width+i] * l.gamma[i]
+            l.gradInputs.Data[b*width+i] = l.invStd[i] / batch * (batch*dxhat - sumDxhat - l.xhat.Data[b*width+i]*sumDxhatXhat)
+        }
+        l.gradGamma[i] = sumGradXhat / batch
+        l.gradBeta[i] = sumGrad / batch
+    }
+}
+
+// Params returns the scale and the shift
+func (l *BatchNorm) Params() []Vector { return l.params }
+
+// Grads returns the gradients aligned with Params
+func (l *BatchNorm) Grads() []Vector { return l.grads }
+
+// States returns the optimizer state aligned with Params
+func (l *BatchNorm) States() []ParamState { return l.states }
+
+// runningStats exposes the running statistics so that they are saved and
+// restored together with the parameters
+func (l *BatchNorm) runningStats() []Vector {
+    return []Vector{l.runningMean, l.runningVar}
+}
+
+// weightPenalty returns the L1/L2 penalty contributed by weights
+func weightPenalty(weights []Vector, l1, l2 float32) float32 {
+    if l1 == 0 && l2 == 0 {
+        return 0
+    }
+    var penalty float32
+    for _, row := range weights {
+        for _, w := range row {
+            penalty += l1*float32(math.Abs(float64(w))) + l2*w*w
+        }
+    }
+    return penalty
+}
+
+// addWeightPenaltyGrad adds the gradient of the L1/L2 penalty to grads
+func addWeightPenaltyGrad(grads, weights Vector, l1, l2 float32) {
+    if l1 == 0 && l2 == 0 {
+        return
+    }
+    for k, w := range weights {
+        switch {
+        case w > 0:
+            grads[k] += l1
+        case w < 0:
+            grads[k] -= l1
+        }
+        grads[k] += 2 * l2 * w
+    }
+}
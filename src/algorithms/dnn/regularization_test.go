@@ -0,0 +1,31 @@
+package dnn
+
+import "testing"
+
+func TestDropoutSeeded(t *testing.T) {
+    x, y := gradientCheckData(12, 3, 1, MeanSquaredError{})
+    train := func(strategy Strategy) Frame {
+        n := &Network{
+            InputWidth:   3,
+            Layers:       []Layer{&Dense{Width: 6, Activation: Tanh{}}, &Dropout{Rate: 0.5}, &Dense{Width: 1, Activation: Linear{}}},
+            LearningRate: 0.05,
+            BatchSize:    4,
+            Strategy:     strategy,
+            Seed:         3,
+        }
+        if _, err := n.Train(3, x, y); err != nil {
+            t.Fatal(err)
+        }
+        return n.Predict(x)
+    }
+
+    want := train(Sequential{})
+    for _, strategy := range []Strategy{Sequential{}, Sharded{Shards: 3}} {
+        got := train(strategy)
+        for i := range want {
+            if got[i][0] != want[i][0] {
+                t.Fatalf("%T: row %d predicted %g, want %g", strategy, i, got[i][0], want[i][0])
+            }
+        }
+    }
+}
@@ -0,0 +1,65 @@
+package dnn
+
+import "math"
+
+// Schedule computes the learning rate for an epoch from the network's base
+// LearningRate. Training consults it at the start of every epoch.
+type Schedule interface {
+    Rate(epoch int, base float32) float32
+}
+
+// StepDecay multiplies the learning rate by Gamma every StepSize epochs.
+type StepDecay struct {
+    StepSize int
+    Gamma    float32
+}
+
+func (s StepDecay) Rate(epoch int, base float32) float32 {
+    if s.StepSize <= 0 {
+        return base
+    }
+    return base * float32(math.Pow(float64(s.Gamma), float64(epoch/s.StepSize)))
+}
+
+// ExponentialDecay multiplies the learning rate by Gamma every epoch.
+type ExponentialDecay struct {
+    Gamma float32
+}
+
+func (s ExponentialDecay) Rate(epoch int, base float32) float32 {
+    return base * float32(math.Pow(float64(s.Gamma), float64(epoch)))
+}
+
+// CosineAnnealing decays the learning rate from its base value down to
+// MinRate over Epochs epochs following half a cosine wave, and stays at
+// MinRate afterwards.
+type CosineAnnealing struct {
+    Epochs  int
+    MinRate float32
+}
+
+func (s CosineAnnealing) Rate(epoch int, base float32) float32 {
+    if s.Epochs <= 0 || epoch >= s.Epochs {
+        return s.MinRate
+    }
+    progress := float64(epoch) / float64(s.Epochs)
+    return s.MinRate + (base-s.MinRate)*float32(0.5*(1+math.Cos(math.Pi*progress)))
+}
+
+// Warmup ramps the learning rate up linearly over the first Epochs epochs
+// and then hands over to After, which sees epochs counted from the end of the
+// warmup. A nil After keeps the base rate.
+type Warmup struct {
+    Epochs int
+    After  Schedule
+}
+
+func (s Warmup) Rate(epoch int, base float32) float32 {
+    if epoch < s.Epochs {
+        return base * float32(epoch+1) / float32(s.Epochs)
+    }
+    if s.After == nil {
+        return base
+    }
+    return s.After.Rate(epoch-s.Epochs, base)
+}
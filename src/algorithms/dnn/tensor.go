@@ -0,0 +1,277 @@
+package dnn
+
+import "fmt"
+
+// Matrix is a dense row-major matrix of float32 values stored in a single
+// contiguous slice, so that rows are cache friendly and batches can be
+// processed as matrix products. Row returns a Vector view into the storage,
+// which lets row-wise code such as activations and losses work on it without
+// copying.
+type Matrix struct {
+    Rows int
+    Cols int
+    Data []float32
+}
+
+// NewMatrix allocates a zeroed matrix with the given dimensions
+func NewMatrix(rows, cols int) *Matrix {
+    return &Matrix{Rows: rows, Cols: cols, Data: make([]float32, rows*cols)}
+}
+
+// FromFrame copies a Frame into a new matrix. Every row must have the same
+// width.
+func FromFrame(f Frame) *Matrix {
+    if len(f) == 0 {
+        return &Matrix{}
+    }
+    m := NewMatrix(len(f), len(f[0]))
+    for i, row := range f {
+        if len(row) != m.Cols {
+            panic(fmt.Sprintf("row %d has width %d, expected %d", i, len(row), m.Cols))
+        }
+        copy(m.Row(i), row)
+    }
+    return m
+}
+
+// fromFrameWidth is FromFrame for a frame whose width is known, keeping that
+// width when the frame has no rows
+func fromFrameWidth(f Frame, cols int) *Matrix {
+    if len(f) == 0 {
+        return NewMatrix(0, cols)
+    }
+    return FromFrame(f)
+}
+
+// ToFrame copies the matrix into a new Frame
+func (m *Matrix) ToFrame() Frame {
+    f := make(Frame, m.Rows)
+    for i := range f {
+        f[i] = append(Vector(nil), m.Row(i)...)
+    }
+    return f
+}
+
+// Row returns row i as a Vector sharing the matrix storage
+func (m *Matrix) Row(i int) Vector {
+    return m.Data[i*m.Cols : (i+1)*m.Cols : (i+1)*m.Cols]
+}
+
+// At returns the value at row i, column j
+func (m *Matrix) At(i, j int) float32 {
+    return m.Data[i*m.Cols+j]
+}
+
+// Set sets the value at row i, column j
+func (m *Matrix) Set(i, j int, value float32) {
+    m.Data[i*m.Cols+j] = value
+}
+
+// Resize changes the dimensions of the matrix, reusing the existing storage
+// whenever it is large enough. The contents are unspecified afterwards.
+func (m *Matrix) Resize(rows, cols int) {
+    size := rows * cols
+    if cap(m.Data) < size {
+        m.Data = make([]float32, size)
+    }
+    m.Data = m.Data[:size]
+    m.Rows = rows
+    m.Cols = cols
+}
+
+// View makes m a view over rows [start, end) of src, sharing its storage
+func (m *Matrix) View(src *Matrix, start, end int) {
+    m.Rows = end - start
+    m.Cols = src.Cols
+    m.Data = src.Data[start*src.Cols : end*src.Cols]
+}
+
+// Zero sets every value of the matrix to zero
+func (m *Matrix) Zero() {
+    clear(m.Data)
+}
+
+// CopyFrom resizes m to the shape of src and copies its values
+func (m *Matrix) CopyFrom(src *Matrix) {
+    m.Resize(src.Rows, src.Cols)
+    copy(m.Data, src.Data)
+}
+
+// Scale multiplies every value of the matrix by scalar in place
+func (m *Matrix) Scale(scalar float32) {
+    for i := range m.Data {
+        m.Data[i] *= scalar
+    }
+}
+
+// MulElem multiplies m element by element with other in place
+func (m *Matrix) MulElem(other *Matrix) {
+    if len(m.Data) != len(other.Data) {
+        panic("matrices must be of the same shape")
+    }
+    for i := range m.Data {
+        m.Data[i] *= other.Data[i]
+    }
+}
+
+// AddRowVector adds v to every row of m in place
+func (m *Matrix) AddRowVector(v Vector) {
+    for i := 0; i < m.Rows; i++ {
+        row := m.Row(i)
+        for j := range row {
+            row[j] += v[j]
+        }
+    }
+}
+
+// SumRows writes the sum of all rows of m into dst
+func (m *Matrix) SumRows(dst Vector) {
+    clear(dst)
+    for i := 0; i < m.Rows; i++ {
+        row := m.Row(i)
+        for j := range row {
+            dst[j] += row[j]
+        }
+    }
+}
+
+// Block sizes used by the GEMM kernels, chosen so that the working set of a
+// block of rows stays in cache
+const (
+    gemmBlockK = 64
+    gemmBlockN = 256
+)
+
+// MatMul computes dst = a·b, resizing dst as needed. Rows of dst are spread
+// across the strategy and each worker walks cache-sized blocks of a and b.
+func MatMul(dst, a, b *Matrix, strategy Strategy) {
+    var op gemm
+    op.mul(dst, a, b, strategy)
+}
+
+// MatMulTransB computes dst = a·bᵀ, resizing dst as needed. Every value is a
+// dot product of two contiguous rows, walked in blocks of b's rows.
+func MatMulTransB(dst, a, b *Matrix, strategy Strategy) {
+    var op gemm
+    op.mulTransB(dst, a, b, strategy)
+}
+
+// MatMulTransA computes dst = aᵀ·b, resizing dst as needed. Rows of dst,
+// which are columns of a, are spread across the strategy.
+func MatMulTransA(dst, a, b *Matrix, strategy Strategy) {
+    var op gemm
+    op.mulTransA(dst, a, b, strategy)
+}
+
+// gemm holds the operands of a matrix product so that layers can keep one
+// per product they compute. Its row kernels are bound once as method values,
+// which keeps repeated products on the same gemm free of allocations.
+type gemm struct {
+    dst, a, b *Matrix
+    rowsAB    func(start, end int)
+    rowsABT   func(start, end int)
+    rowsATB   func(start, end int)
+}
+
+func (g *gemm) bind(dst, a, b *Matrix) {
+    g.dst, g.a, g.b = dst, a, b
+    if g.rowsAB == nil {
+        g.rowsAB = g.kernelAB
+        g.rowsABT = g.kernelABT
+        g.rowsATB = g.kernelATB
+    }
+}
+
+func (g *gemm) mul(dst, a, b *Matrix, strategy Strategy) {
+    if a.Cols != b.Rows {
+        panic(fmt.Sprintf("cannot multiply %dx%d by %dx%d", a.Rows, a.Cols, b.Rows, b.Cols))
+    }
+    dst.Resize(a.Rows, b.Cols)
+    g.bind(dst, a, b)
+    strategy.Run(a.Rows, g.rowsAB)
+}
+
+func (g *gemm) mulTransB(dst, a, b *Matrix, strategy Strategy) {
+    if a.Cols != b.Cols {
+        panic(fmt.Sprintf("cannot multiply %dx%d by transposed %dx%d", a.Rows, a.Cols, b.Rows, b.Cols))
+    }
+    dst.Resize(a.Rows, b.Rows)
+    g.bind(dst, a, b)
+    strategy.Run(a.Rows, g.rowsABT)
+}
+
+func (g *gemm) mulTransA(dst, a, b *Matrix, strategy Strategy) {
+    if a.Rows != b.Rows {
+        panic(fmt.Sprintf("cannot multiply transposed %dx%d by %dx%d", a.Rows, a.Cols, b.Rows, b.Cols))
+    }
+    dst.Resize(a.Cols, b.Cols)
+    g.bind(dst, a, b)
+    strategy.Run(a.Cols, g.rowsATB)
+}
+
+// kernelAB computes rows [start, end) of a·b, blocking over the shared
+// dimension and the columns of b
+func (g *gemm) kernelAB(start, end int) {
+    dst, a, b := g.dst, g.a, g.b
+    K, N := a.Cols, b.Cols
+    clear(dst.Data[start*N : end*N])
+    for kk := 0; kk < K; kk += gemmBlockK {
+        kEnd := min(kk+gemmBlockK, K)
+        for jj := 0; jj < N; jj += gemmBlockN {
+            jEnd := min(jj+gemmBlockN, N)
+            for i := start; i < end; i++ {
+                out := dst.Data[i*N+jj : i*N+jEnd]
+                for k := kk; k < kEnd; k++ {
+                    aik := a.Data[i*K+k]
+                    if aik == 0 {
+                        continue
+                    }
+                    in := b.Data[k*N+jj : k*N+jEnd]
+                    for j := range out {
+                        out[j] += aik * in[j]
+                    }
+                }
+            }
+        }
+    }
+}
+
+// kernelABT computes rows [start, end) of a·bᵀ, blocking over the rows of b
+func (g *gemm) kernelABT(start, end int) {
+    dst, a, b := g.dst, g.a, g.b
+    N := b.Rows
+    for jj := 0; jj < N; jj += gemmBlockK {
+        jEnd := min(jj+gemmBlockK, N)
+        for i := start; i < end; i++ {
+            row := a.Row(i)
+            out := dst.Data[i*N : (i+1)*N]
+            for j := jj; j < jEnd; j++ {
+                out[j] = DotProduct(row, b.Row(j))
+            }
+        }
+    }
+}
+
+// kernelATB computes rows [start, end) of aᵀ·b, blocking over the shared
+// dimension
+func (g *gemm) kernelATB(start, end int) {
+    dst, a, b := g.dst, g.a, g.b
+    K, M, N := a.Rows, a.Cols, b.Cols
+    clear(dst.Data[start*N : end*N])
+    for kk := 0; kk < K; kk += gemmBlockK {
+        kEnd := min(kk+gemmBlockK, K)
+        for i := start; i < end; i++ {
+            out := dst.Data[i*N : (i+1)*N]
+            for k := kk; k < kEnd; k++ {
+                aki := a.Data[k*M+i]
+                if aki == 0 {
+                    continue
+                }
+                in := b.Data[k*N : (k+1)*N]
+                for j := range out {
+                    out[j] += aki * in[j]
+                }
+            }
+        }
+    }
+}
@@ -91,17 +91,18 @@ func SigmoidDerivative(x float32) float32 {
     return sig * (1 - sig)
 }
 
-// CalculateAccuracy calcula la precisión de las predicciones
+// CalculateAccuracy calcula la precisión de las predicciones. Las filas de
+// una sola salida se umbralizan en 0.5 y las de varias salidas se comparan
+// por su argmax contra la clase en actual.
 func CalculateAccuracy(predictions Frame, actual []float64) float64 {
+    if len(actual) == 0 {
+        return 0
+    }
     correct := 0
     for i, prediction := range predictions {
-        predictedLabel := 0
-        if prediction[0] >= 0.5 {
-            predictedLabel = 1
-        }
-        if float64(predictedLabel) == actual[i] {
+        if ClassOf(prediction) == classLabel(actual[i]) {
             correct++
         }
     }
     return float64(correct) / float64(len(actual))
-}
\ No newline at end of file
+}
@@ -0,0 +1,202 @@
+package fc
+
+import "sort"
+
+// Recomendador de los k ítems más adecuados para un usuario que no estén
+// entre los que ya calificó (rated). Lo implementan MatrixFactorization,
+// ImplicitFactorization, los recomendadores de referencia de este archivo
+// y Fallback; cualquier otro puede adaptarse con RecommenderFunc.
+type Recommender interface {
+    Recommend(user string, rated map[string]float64, k int) []string
+}
+
+// Adaptador de una función al interfaz Recommender
+type RecommenderFunc func(user string, rated map[string]float64, k int) []string
+
+func (f RecommenderFunc) Recommend(user string, rated map[string]float64, k int) []string {
+    return f(user, rated, k)
+}
+
+// Recomendador no personalizado: la misma lista de ítems, ordenada por una
+// puntuación fija, para todos los usuarios
+type ItemRanking struct {
+    ranked []scoredItem
+}
+
+// Ordenar los ítems de la matriz según score, de mayor a menor
+func newItemRanking(m *RatingMatrix, score func(i int32) float64) *ItemRanking {
+    r := &ItemRanking{ranked: make([]scoredItem, m.items.Len())}
+    for i := range r.ranked {
+        r.ranked[i] = scoredItem{item: m.items.Name(int32(i)), id: i, score: score(int32(i))}
+    }
+    sort.Slice(r.ranked, func(a, b int) bool { return ranksBelow(r.ranked[b], r.ranked[a]) })
+    return r
+}
+
+// Ranking por popularidad: número de calificaciones de cada ítem
+func NewPopularity(ratings RatingStore) *ItemRanking {
+    m := NewRatingMatrix(ratings)
+    return newItemRanking(m, func(i int32) float64 {
+        users, _ := m.ItemColumn(i)
+        return float64(len(users))
+    })
+}
+
+// Ranking por media bayesiana: (C·μ + Σ r) / (C + n), con μ la media
+// global y n las calificaciones del ítem, de modo que un ítem con pocas
+// calificaciones queda cerca de la media global. C es confidence o, si es
+// 0, el número medio de calificaciones por ítem.
+func NewBayesianAverage(ratings RatingStore, confidence float64) *ItemRanking {
+    m := NewRatingMatrix(ratings)
+    globalMean := statsOfValues(m.rowValues).mean
+    if confidence <= 0 {
+        confidence = safeRatio(float64(m.NumRatings()), float64(m.items.Len()))
+    }
+    return newItemRanking(m, func(i int32) float64 {
+        users, _ := m.ItemColumn(i)
+        n := float64(len(users))
+        return (confidence*globalMean + n*m.itemMeans[i]) / (confidence + n)
+    })
+}
+
+// Recomendar los k primeros ítems del ranking que el usuario no calificó
+func (r *ItemRanking) Recommend(user string, rated map[string]float64, k int) []string {
+    recommendations := make([]string, 0, max(k, 0))
+    for _, candidate := range r.ranked {
+        if len(recommendations) >= k {
+            break
+        }
+        if _, exists := rated[candidate.item]; !exists {
+            recommendations = append(recommendations, candidate.item)
+        }
+    }
+    return recommendations
+}
+
+// Modelo de sesgos: la calificación se estima como μ + b_u + b_i, con μ la
+// media global y b_u y b_i las desviaciones regularizadas del usuario y del
+// ítem. Los valores a cero toman los valores por defecto indicados.
+type BiasBaseline struct {
+    Iterations int     // pasadas alternando ítems y usuarios, por defecto 10
+    UserLambda float64 // regularización de b_u, por defecto 10
+    ItemLambda float64 // regularización de b_i, por defecto 25
+
+    ratings    *RatingMatrix
+    userBias   []float64
+    itemBias   []float64
+    globalMean float64
+}
+
+// Ajustar los sesgos alternando b_i = Σ (r - μ - b_u) / (ItemLambda + n_i)
+// sobre los usuarios de cada ítem y b_u = Σ (r - μ - b_i) / (UserLambda +
+// n_u) sobre los ítems de cada usuario
+func (b *BiasBaseline) Train(ratings RatingStore) {
+    if b.Iterations <= 0 {
+        b.Iterations = 10
+    }
+    if b.UserLambda == 0 {
+        b.UserLambda = 10
+    }
+    if b.ItemLambda == 0 {
+        b.ItemLambda = 25
+    }
+    m := NewRatingMatrix(ratings)
+    b.ratings = m
+    b.globalMean = statsOfValues(m.rowValues).mean
+    b.userBias = make([]float64, m.users.Len())
+    b.itemBias = make([]float64, m.items.Len())
+
+    for it := 0; it < b.Iterations; it++ {
+        for i := range b.itemBias {
+            users, values := m.ItemColumn(int32(i))
+            var sum float64
+            for x, u := range users {
+                sum += float64(values[x]) - b.globalMean - b.userBias[u]
+            }
+            b.itemBias[i] = sum / (b.ItemLambda + float64(len(users)))
+        }
+        for u := range b.userBias {
+            items, values := m.UserRow(int32(u))
+            var sum float64
+            for x, i := range items {
+                sum += float64(values[x]) - b.globalMean - b.itemBias[i]
+            }
+            b.userBias[u] = sum / (b.UserLambda + float64(len(items)))
+        }
+    }
+}
+
+// Estimar la calificación de un usuario a un ítem con la parte conocida de
+// μ + b_u + b_i; known indica si ambos estaban en el entrenamiento
+func (b *BiasBaseline) Predict(user, item string) (rating float64, known bool) {
+    if b.ratings == nil {
+        return 0, false
+    }
+    u, userKnown := b.ratings.users.ID(user)
+    i, itemKnown := b.ratings.items.ID(item)
+    rating = b.globalMean
+    if userKnown {
+        rating += b.userBias[u]
+    }
+    if itemKnown {
+        rating += b.itemBias[i]
+    }
+    return rating, userKnown && itemKnown
+}
+
+// Recomendar los k ítems no calificados con mayor sesgo. El sesgo del
+// usuario no cambia el orden, así que equivale a un ranking por b_i.
+func (b *BiasBaseline) Recommend(user string, rated map[string]float64, k int) []string {
+    if b.ratings == nil || k <= 0 {
+        return []string{}
+    }
+    h := make(scoreHeap, 0, k+1)
+    for i, bias := range b.itemBias {
+        item := b.ratings.items.Name(int32(i))
+        if _, exists := rated[item]; !exists {
+            h.offer(scoredItem{item: item, id: i, score: bias}, k)
+        }
+    }
+    ranked := h.drain()
+    recommendations := make([]string, len(ranked))
+    for x, r := range ranked {
+        recommendations[x] = r.item
+    }
+    return recommendations
+}
+
+// Etapa de una cadena de recomendadores. Se omite para los usuarios con
+// menos de MinRatings calificaciones.
+type FallbackStage struct {
+    Recommender Recommender
+    MinRatings  int
+}
+
+// Cadena de recomendadores, del más personalizado al más general. Cada
+// etapa completa la lista con ítems que las anteriores no dieron, de modo
+// que un usuario desconocido o con pocas calificaciones recibe igualmente k
+// recomendaciones si la última etapa es un ranking no personalizado.
+type Fallback []FallbackStage
+
+func (f Fallback) Recommend(user string, rated map[string]float64, k int) []string {
+    recommendations := make([]string, 0, max(k, 0))
+    seen := make(map[string]struct{})
+    for _, stage := range f {
+        if len(recommendations) >= k {
+            break
+        }
+        if len(rated) < stage.MinRatings {
+            continue
+        }
+        // Se piden k ítems a cada etapa porque algunos pueden estar ya en
+        // la lista
+        for _, item := range stage.Recommender.Recommend(user, rated, k) {
+            if _, exists := seen[item]; exists || len(recommendations) >= k {
+                continue
+            }
+            seen[item] = struct{}{}
+            recommendations = append(recommendations, item)
+        }
+    }
+    return recommendations
+}
@@ -1,91 +1,421 @@
 package fc
 
 import (
-    "math"
-    "sort"
+    "fmt"
+    "runtime"
+    "strconv"
     "sync"
+    "sync/atomic"
 )
 
-// Estructura para almacenar las calificaciones
+// Número de particiones del almacén concurrente. Cada partición tiene su
+// propio RWMutex, de modo que escrituras sobre usuarios distintos rara vez
+// compiten por el mismo candado.
+const ratingShards = 64
+
+// Partición de usuarios protegida por su propio candado, con las
+// calificaciones de cada usuario por identificador de ítem
+type ratingShard struct {
+    mu   sync.RWMutex
+    data map[int32]map[int32]float32
+}
+
+// Partición del índice invertido ítem → usuarios que lo calificaron
+type itemShard struct {
+    mu    sync.RWMutex
+    users map[int32]map[int32]struct{}
+}
+
+// Diccionario que puede compartirse entre goroutines
+type sharedDictionary struct {
+    mu   sync.RWMutex
+    dict *Dictionary
+}
+
+// Obtener el identificador de un nombre, asignándole uno nuevo si no lo
+// tenía. Solo se toma el candado de escritura para los nombres nuevos.
+func (d *sharedDictionary) intern(name string) int32 {
+    if id, exists := d.id(name); exists {
+        return id
+    }
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    return d.dict.Intern(name)
+}
+
+func (d *sharedDictionary) id(name string) (int32, bool) {
+    d.mu.RLock()
+    defer d.mu.RUnlock()
+    return d.dict.ID(name)
+}
+
+func (d *sharedDictionary) name(id int32) string {
+    d.mu.RLock()
+    defer d.mu.RUnlock()
+    return d.dict.Name(id)
+}
+
+// Nombres registrados hasta ahora. Los nombres solo se añaden al final, así
+// que el slice puede leerse sin candado.
+func (d *sharedDictionary) names() []string {
+    d.mu.RLock()
+    defer d.mu.RUnlock()
+    return d.dict.Names()
+}
+
+// Estructura para almacenar las calificaciones de forma segura entre
+// goroutines. Usuarios e ítems se internan en diccionarios compartidos y
+// los usuarios se reparten entre particiones según su identificador; las
+// lecturas toman el candado de lectura de la partición y las altas,
+// modificaciones y bajas el de escritura, así que pueden servirse
+// recomendaciones mientras se cargan o actualizan calificaciones. Un índice
+// invertido, particionado igual por ítem, da los usuarios que calificaron
+// cada ítem. Las escrituras actualizan el índice sin soltar el candado de
+// la partición del usuario, así que el índice nunca se desincroniza de las
+// calificaciones. Los candados se toman siempre en el orden usuario → ítem
+// → diccionario y ninguna lectura toma los de usuario e ítem a la vez, por
+// lo que no hay interbloqueos. Los recomendadores leen una instantánea
+// como RatingMatrix (ver Matrix), sin bloquear las escrituras.
 type RatingsConcurrent struct {
-    data map[string]map[string]float64
+    shards [ratingShards]ratingShard
+    index  [ratingShards]itemShard
+    users  sharedDictionary
+    items  sharedDictionary
+
+    // Número de escrituras, para saber si la instantánea está al día
+    version         atomic.Uint64
+    snapshotMu      sync.Mutex
+    snapshot        *RatingMatrix
+    snapshotVersion uint64
 }
 
 // Constructor para la estructura RatingsConcurrent
 func NewRatingsConcurrent() *RatingsConcurrent {
-    return &RatingsConcurrent{data: make(map[string]map[string]float64)}
+    r := &RatingsConcurrent{}
+    r.users.dict = NewDictionary()
+    r.items.dict = NewDictionary()
+    for i := range r.shards {
+        r.shards[i].data = make(map[int32]map[int32]float32)
+        r.index[i].users = make(map[int32]map[int32]struct{})
+    }
+    return r
 }
 
-// Añadir una calificación
+// Partición a la que pertenece un usuario
+func (r *RatingsConcurrent) shard(u int32) *ratingShard {
+    return &r.shards[u%ratingShards]
+}
+
+// Partición del índice invertido a la que pertenece un ítem
+func (r *RatingsConcurrent) itemShard(i int32) *itemShard {
+    return &r.index[i%ratingShards]
+}
+
+// Registrar en el índice invertido que u calificó i. Se llama con el
+// candado de escritura de la partición de u tomado.
+func (r *RatingsConcurrent) indexRating(u, i int32) {
+    s := r.itemShard(i)
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, exists := s.users[i]; !exists {
+        s.users[i] = make(map[int32]struct{})
+    }
+    s.users[i][u] = struct{}{}
+}
+
+// Quitar del índice invertido la calificación de u a i. Se llama con el
+// candado de escritura de la partición de u tomado.
+func (r *RatingsConcurrent) unindexRating(u, i int32) {
+    s := r.itemShard(i)
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    delete(s.users[i], u)
+    if len(s.users[i]) == 0 {
+        delete(s.users, i)
+    }
+}
+
+// Obtener los usuarios que calificaron un ítem
+func (r *RatingsConcurrent) ItemUsersConcurrent(item string) []string {
+    i, exists := r.items.id(item)
+    if !exists {
+        return []string{}
+    }
+    s := r.itemShard(i)
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    users := make([]string, 0, len(s.users[i]))
+    for u := range s.users[i] {
+        users = append(users, r.users.name(u))
+    }
+    return users
+}
+
+// Añadir o actualizar una calificación
 func (r *RatingsConcurrent) AddRatingConcurrent(user, item string, rating float64) {
-    if _, exists := r.data[user]; !exists {
-        r.data[user] = make(map[string]float64)
+    u, i := r.users.intern(user), r.items.intern(item)
+    s := r.shard(u)
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, exists := s.data[u]; !exists {
+        s.data[u] = make(map[int32]float32)
+    }
+    _, existed := s.data[u][i]
+    s.data[u][i] = float32(rating)
+    if !existed {
+        r.indexRating(u, i)
     }
-    r.data[user][item] = rating
+    r.version.Add(1)
 }
 
-// Calcular la similitud del coseno entre dos usuarios
-func CosineSimilarityConcurrent(ratings *RatingsConcurrent, user1, user2 string) float64 {
-    commonItems := make(map[string]bool)
-    for item := range ratings.data[user1] {
-        if _, exists := ratings.data[user2][item]; exists {
-            commonItems[item] = true
+// Eliminar una calificación, indicando si existía. El usuario desaparece al
+// quedarse sin calificaciones.
+func (r *RatingsConcurrent) RemoveRatingConcurrent(user, item string) bool {
+    u, userExists := r.users.id(user)
+    i, itemExists := r.items.id(item)
+    if !userExists || !itemExists {
+        return false
+    }
+    s := r.shard(u)
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, exists := s.data[u][i]; !exists {
+        return false
+    }
+    delete(s.data[u], i)
+    if len(s.data[u]) == 0 {
+        delete(s.data, u)
+    }
+    r.unindexRating(u, i)
+    r.version.Add(1)
+    return true
+}
+
+// Eliminar un usuario con todas sus calificaciones, indicando si existía
+func (r *RatingsConcurrent) RemoveUserConcurrent(user string) bool {
+    u, exists := r.users.id(user)
+    if !exists {
+        return false
+    }
+    s := r.shard(u)
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    ratings, exists := s.data[u]
+    if !exists {
+        return false
+    }
+    delete(s.data, u)
+    for i := range ratings {
+        r.unindexRating(u, i)
+    }
+    r.version.Add(1)
+    return true
+}
+
+// Obtener la calificación de un usuario a un ítem
+func (r *RatingsConcurrent) RatingConcurrent(user, item string) (float64, bool) {
+    u, userExists := r.users.id(user)
+    i, itemExists := r.items.id(item)
+    if !userExists || !itemExists {
+        return 0, false
+    }
+    s := r.shard(u)
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    rating, exists := s.data[u][i]
+    return float64(rating), exists
+}
+
+// Obtener una copia de las calificaciones de un usuario, que el llamador
+// puede recorrer sin candados
+func (r *RatingsConcurrent) UserRatingsConcurrent(user string) map[string]float64 {
+    u, exists := r.users.id(user)
+    if !exists {
+        return map[string]float64{}
+    }
+    s := r.shard(u)
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    return namedRatings(s.data[u], r.items.name)
+}
+
+// Obtener los usuarios con al menos una calificación
+func (r *RatingsConcurrent) UsersConcurrent() []string {
+    var users []string
+    for i := range r.shards {
+        s := &r.shards[i]
+        s.mu.RLock()
+        for u := range s.data {
+            users = append(users, r.users.name(u))
         }
+        s.mu.RUnlock()
     }
+    return users
+}
 
-    if len(commonItems) == 0 {
-        return 0.0
+// Recorrer las calificaciones de cada usuario. Cada partición se lee bajo su
+// candado, entregando a fn copias que puede conservar.
+func (r *RatingsConcurrent) EachUser(fn func(user string, ratings map[string]float64)) {
+    for _, user := range r.UsersConcurrent() {
+        if ratings := r.UserRatingsConcurrent(user); len(ratings) > 0 {
+            fn(user, ratings)
+        }
     }
+}
 
-    var sum1, sum2, sumProduct float64
-    for item := range commonItems {
-        sum1 += ratings.data[user1][item] * ratings.data[user1][item]
-        sum2 += ratings.data[user2][item] * ratings.data[user2][item]
-        sumProduct += ratings.data[user1][item] * ratings.data[user2][item]
+// Número de usuarios con al menos una calificación
+func (r *RatingsConcurrent) NumUsersConcurrent() int {
+    total := 0
+    for i := range r.shards {
+        s := &r.shards[i]
+        s.mu.RLock()
+        total += len(s.data)
+        s.mu.RUnlock()
     }
+    return total
+}
 
-    return sumProduct / (math.Sqrt(sum1) * math.Sqrt(sum2))
+// Instantánea de las calificaciones como RatingMatrix. Se reconstruye en la
+// primera llamada tras alguna escritura, recorriendo las particiones una a
+// una, y se comparte entre las llamadas siguientes; las escrituras
+// posteriores no la modifican. Si hay escrituras durante la reconstrucción,
+// la instantánea puede incluir parte de ellas y se reconstruye de nuevo en
+// la siguiente llamada.
+func (r *RatingsConcurrent) Matrix() *RatingMatrix {
+    r.snapshotMu.Lock()
+    defer r.snapshotMu.Unlock()
+    version := r.version.Load()
+    if r.snapshot != nil && r.snapshotVersion == version {
+        return r.snapshot
+    }
+    var entries []matrixEntry
+    for x := range r.shards {
+        s := &r.shards[x]
+        s.mu.RLock()
+        for u, ratings := range s.data {
+            for i, rating := range ratings {
+                entries = append(entries, matrixEntry{user: u, item: i, rating: rating})
+            }
+        }
+        s.mu.RUnlock()
+    }
+    // Los identificadores se internan antes de guardar las calificaciones,
+    // así que los diccionarios, leídos después, los incluyen todos
+    r.snapshot = newSortedRatingMatrix(r.users.names(), r.items.names(), entries)
+    r.snapshotVersion = version
+    return r.snapshot
 }
 
-// Generar recomendaciones para un usuario de manera concurrente
-func RecommendConcurrent(ratings *RatingsConcurrent, user string, k int) []string {
-    scores := make(map[string]float64)
-    similaritySums := make(map[string]float64)
-    var mu sync.Mutex
+// Convierte un registro de ratings.csv en usuario, ítem y calificación
+type RecordParser func(record []string) (user, item string, rating float64, err error)
+
+// Interpreta registros con el formato userId,movieId,rating[,timestamp],
+// usando los identificadores tal cual
+func ParseRatingRecord(record []string) (string, string, float64, error) {
+    if len(record) < 3 {
+        return "", "", 0, fmt.Errorf("se esperaban al menos 3 columnas, hay %d", len(record))
+    }
+    rating, err := strconv.ParseFloat(record[2], 64)
+    if err != nil {
+        return "", "", 0, fmt.Errorf("error al convertir la calificación '%s': %w", record[2], err)
+    }
+    return record[0], record[1], rating, nil
+}
+
+// Interpreta registros como ParseRatingRecord, anteponiendo un prefijo a los
+// identificadores de usuario e ítem (por ejemplo "User" e "Item")
+func PrefixedRecordParser(userPrefix, itemPrefix string) RecordParser {
+    return func(record []string) (string, string, float64, error) {
+        user, item, rating, err := ParseRatingRecord(record)
+        return userPrefix + user, itemPrefix + item, rating, err
+    }
+}
+
+// Cargar en paralelo un bloque de registros, repartidos entre workers
+// goroutines (por defecto, tantas como CPUs). parse interpreta cada registro
+// y por defecto es ParseRatingRecord. Se devuelve el primer error
+// encontrado, junto con la fila que lo produjo; las filas válidas se cargan
+// igualmente.
+func (r *RatingsConcurrent) LoadRatingsConcurrent(records [][]string, parse RecordParser, workers int) error {
+    if parse == nil {
+        parse = ParseRatingRecord
+    }
+    if workers <= 0 {
+        workers = runtime.NumCPU()
+    }
+    chunkSize := (len(records) + workers - 1) / workers
+    if chunkSize == 0 {
+        return nil
+    }
+
     var wg sync.WaitGroup
-    for otherUser := range ratings.data {
-        if otherUser == user {
-            continue
-        }
+    var once sync.Once
+    var firstErr error
+    for start := 0; start < len(records); start += chunkSize {
+        end := min(start+chunkSize, len(records))
         wg.Add(1)
-        go func(otherUser string) {
+        go func(start, end int) {
             defer wg.Done()
-            similarity := CosineSimilarityConcurrent(ratings, user, otherUser)
-            if similarity <= 0 {
-                return
-            }
-            mu.Lock()
-            defer mu.Unlock()
-            for item, rating := range ratings.data[otherUser] {
-                if _, exists := ratings.data[user][item]; exists {
+            for i := start; i < end; i++ {
+                user, item, rating, err := parse(records[i])
+                if err != nil {
+                    once.Do(func() { firstErr = fmt.Errorf("fila %d: %w", i, err) })
                     continue
                 }
-                scores[item] += similarity * rating
-                similaritySums[item] += similarity
+                r.AddRatingConcurrent(user, item, rating)
             }
-        }(otherUser)
+        }(start, end)
     }
     wg.Wait()
-    recommendations := make([]string, 0, k)
-    for item := range scores {
-        scores[item] /= similaritySums[item]
-        recommendations = append(recommendations, item)
-    }
-    sort.Slice(recommendations, func(i, j int) bool {
-        return scores[recommendations[i]] > scores[recommendations[j]]
-    })
-    if len(recommendations) > k {
-        return recommendations[:k]
-    }
-    return recommendations
-}
\ No newline at end of file
+    return firstErr
+}
+
+// Calcular la similitud del coseno entre dos usuarios
+func CosineSimilarityConcurrent(ratings *RatingsConcurrent, user1, user2 string) float64 {
+    return SimilarityConcurrent(ratings, Cosine{}, user1, user2)
+}
+
+// Calcular la similitud entre dos usuarios con la medida indicada, sobre la
+// instantánea del almacén
+func SimilarityConcurrent(ratings *RatingsConcurrent, similarity Similarity, user1, user2 string) float64 {
+    return ratings.Matrix().userSimilarity(similarity, user1, user2)
+}
+
+// Repartir los índices de 0 a n-1 entre un número acotado de workers
+// (tantos como CPUs) que los toman de una cola común. fn recibe el número
+// de worker, para que cada uno acumule en sus propias estructuras.
+func parallelFor(n int, fn func(worker, i int)) {
+    workers := min(runtime.NumCPU(), n)
+    var next atomic.Int64
+    var wg sync.WaitGroup
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+        go func(worker int) {
+            defer wg.Done()
+            for {
+                i := int(next.Add(1)) - 1
+                if i >= n {
+                    return
+                }
+                fn(worker, i)
+            }
+        }(w)
+    }
+    wg.Wait()
+}
+
+// Generar las topN recomendaciones para un usuario de manera concurrente,
+// con RecommendCSR sobre la instantánea del almacén. Solo se consideran
+// los usuarios que comparten algún ítem con él, sus similitudes se calculan
+// en paralelo y se combinan las calificaciones de los opts.Neighbors más
+// similares.
+func RecommendConcurrent(ratings *RatingsConcurrent, user string, topN int, opts UserBasedOptions) []string {
+    return RecommendCSR(ratings.Matrix(), user, topN, opts)
+}
+
+// Estimar la calificación de un usuario a un ítem a partir de los
+// opts.Neighbors usuarios más similares a él que lo calificaron, con
+// PredictRatingCSR sobre la instantánea del almacén
+func PredictRatingConcurrent(ratings *RatingsConcurrent, user, item string, opts UserBasedOptions) Prediction {
+    return PredictRatingCSR(ratings.Matrix(), user, item, opts)
+}
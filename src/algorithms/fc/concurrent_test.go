@@ -0,0 +1,153 @@
+package fc
+
+import (
+    "fmt"
+    "math/rand"
+    "reflect"
+    "sort"
+    "strconv"
+    "sync"
+    "testing"
+)
+
+// Calificaciones deterministas de users usuarios sobre items ítems, con
+// aproximadamente la densidad indicada
+func testRatingRecords(users, items int, density float64) [][]string {
+    rng := rand.New(rand.NewSource(11))
+    var records [][]string
+    for u := 0; u < users; u++ {
+        for i := 0; i < items; i++ {
+            if rng.Float64() < density {
+                rating := strconv.Itoa(1 + rng.Intn(5))
+                records = append(records, []string{fmt.Sprint("u", u), fmt.Sprint("i", i), rating})
+            }
+        }
+    }
+    return records
+}
+
+var storeOptions = []struct {
+    name string
+    opts UserBasedOptions
+}{
+    {name: "cosine", opts: UserBasedOptions{}},
+    {name: "pearson mean centering", opts: UserBasedOptions{Similarity: Pearson{}, Neighbors: 5, Normalization: MeanCentering}},
+    {name: "adjusted cosine z-score", opts: UserBasedOptions{Similarity: AdjustedCosine{}, MinCoRated: 3, Normalization: ZScore}},
+}
+
+func TestStoresAgree(t *testing.T) {
+    records := testRatingRecords(30, 20, 0.4)
+
+    sequencial := NewRatingsSequencial()
+    for _, record := range records {
+        user, item, rating, err := ParseRatingRecord(record)
+        if err != nil {
+            t.Fatal(err)
+        }
+        sequencial.AddRatingSequencial(user, item, rating)
+    }
+    concurrent := NewRatingsConcurrent()
+    if err := concurrent.LoadRatingsConcurrent(records, nil, 4); err != nil {
+        t.Fatal(err)
+    }
+    matrix, err := LoadRatingMatrix(records, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for _, c := range storeOptions {
+        for u := 0; u < 30; u++ {
+            user := fmt.Sprint("u", u)
+            want := RecommendCSR(matrix, user, 5, c.opts)
+            if got := RecommendSequencial(sequencial, user, 5, c.opts); !reflect.DeepEqual(got, want) {
+                t.Errorf("%s: RecommendSequencial(%s) = %v, CSR %v", c.name, user, got, want)
+            }
+            if got := RecommendConcurrent(concurrent, user, 5, c.opts); !reflect.DeepEqual(got, want) {
+                t.Errorf("%s: RecommendConcurrent(%s) = %v, CSR %v", c.name, user, got, want)
+            }
+            for i := 0; i < 20; i++ {
+                item := fmt.Sprint("i", i)
+                want := PredictRatingCSR(matrix, user, item, c.opts)
+                if got := PredictRatingSequencial(sequencial, user, item, c.opts); got != want {
+                    t.Errorf("%s: PredictRatingSequencial(%s, %s) = %+v, CSR %+v", c.name, user, item, got, want)
+                }
+                if got := PredictRatingConcurrent(concurrent, user, item, c.opts); got != want {
+                    t.Errorf("%s: PredictRatingConcurrent(%s, %s) = %+v, CSR %+v", c.name, user, item, got, want)
+                }
+            }
+        }
+    }
+}
+
+// Pensado para ejecutarse con -race: escrituras, lecturas y recomendaciones
+// simultáneas, tras las que el índice invertido y la instantánea deben
+// coincidir con las calificaciones almacenadas
+func TestConcurrentMutateWhileRecommend(t *testing.T) {
+    ratings := NewRatingsConcurrent()
+    if err := ratings.LoadRatingsConcurrent(testRatingRecords(30, 20, 0.4), nil, 4); err != nil {
+        t.Fatal(err)
+    }
+
+    var wg sync.WaitGroup
+    for w := 0; w < 4; w++ {
+        wg.Add(1)
+        go func(w int) {
+            defer wg.Done()
+            rng := rand.New(rand.NewSource(int64(w)))
+            for n := 0; n < 300; n++ {
+                user, item := fmt.Sprint("u", rng.Intn(35)), fmt.Sprint("i", rng.Intn(20))
+                switch rng.Intn(10) {
+                case 0:
+                    ratings.RemoveUserConcurrent(user)
+                case 1, 2, 3:
+                    ratings.RemoveRatingConcurrent(user, item)
+                default:
+                    ratings.AddRatingConcurrent(user, item, float64(1+rng.Intn(5)))
+                }
+            }
+        }(w)
+    }
+    for r := 0; r < 4; r++ {
+        wg.Add(1)
+        go func(r int) {
+            defer wg.Done()
+            for n := 0; n < 50; n++ {
+                user, item := fmt.Sprint("u", (r*7+n)%30), fmt.Sprint("i", n%20)
+                RecommendConcurrent(ratings, user, 5, storeOptions[n%len(storeOptions)].opts)
+                PredictRatingConcurrent(ratings, user, item, UserBasedOptions{})
+                ratings.ItemUsersConcurrent(item)
+            }
+        }(r)
+    }
+    wg.Wait()
+
+    itemUsers := make(map[string][]string)
+    total := 0
+    sequencial := NewRatingsSequencial()
+    ratings.EachUser(func(user string, userRatings map[string]float64) {
+        for item, rating := range userRatings {
+            itemUsers[item] = append(itemUsers[item], user)
+            sequencial.AddRatingSequencial(user, item, rating)
+            total++
+        }
+    })
+    for i := 0; i < 20; i++ {
+        item := fmt.Sprint("i", i)
+        got, want := ratings.ItemUsersConcurrent(item), itemUsers[item]
+        sort.Strings(got)
+        sort.Strings(want)
+        if len(got) != len(want) || (len(want) > 0 && !reflect.DeepEqual(got, want)) {
+            t.Errorf("ItemUsersConcurrent(%s) = %v, want %v", item, got, want)
+        }
+    }
+    if got := ratings.Matrix().NumRatings(); got != total {
+        t.Errorf("Matrix().NumRatings() = %d, want %d", got, total)
+    }
+    for u := 0; u < 35; u++ {
+        user := fmt.Sprint("u", u)
+        got, want := RecommendConcurrent(ratings, user, 5, UserBasedOptions{}), RecommendSequencial(sequencial, user, 5, UserBasedOptions{})
+        if !reflect.DeepEqual(got, want) {
+            t.Errorf("RecommendConcurrent(%s) = %v after writes, want %v", user, got, want)
+        }
+    }
+}
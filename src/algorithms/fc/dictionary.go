@@ -0,0 +1,57 @@
+package fc
+
+// Diccionario bidireccional entre nombres e identificadores enteros
+// consecutivos, asignados en orden de aparición. Permite guardar usuarios e
+// ítems como int32 y recuperar su nombre solo al presentar resultados.
+type Dictionary struct {
+    names []string
+    ids   map[string]int32
+}
+
+// Constructor para la estructura Dictionary
+func NewDictionary() *Dictionary {
+    return &Dictionary{ids: make(map[string]int32)}
+}
+
+// Obtener el identificador de un nombre, asignándole uno nuevo si no lo
+// tenía
+func (d *Dictionary) Intern(name string) int32 {
+    if id, exists := d.ids[name]; exists {
+        return id
+    }
+    id := int32(len(d.names))
+    d.names = append(d.names, name)
+    d.ids[name] = id
+    return id
+}
+
+// Obtener el identificador de un nombre ya registrado
+func (d *Dictionary) ID(name string) (int32, bool) {
+    id, exists := d.ids[name]
+    return id, exists
+}
+
+// Obtener el nombre de un identificador
+func (d *Dictionary) Name(id int32) string {
+    return d.names[id]
+}
+
+// Nombres registrados, en orden de identificador
+func (d *Dictionary) Names() []string {
+    return d.names
+}
+
+// Número de nombres registrados
+func (d *Dictionary) Len() int {
+    return len(d.names)
+}
+
+// Copia de unas calificaciones guardadas por identificador de ítem, con
+// los ítems por nombre
+func namedRatings(ratings map[int32]float32, name func(int32) string) map[string]float64 {
+    named := make(map[string]float64, len(ratings))
+    for i, rating := range ratings {
+        named[name(i)] = float64(rating)
+    }
+    return named
+}
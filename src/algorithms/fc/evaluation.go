@@ -0,0 +1,302 @@
+package fc
+
+import (
+    "fmt"
+    "math"
+    "math/rand"
+    "runtime"
+    "sort"
+    "strconv"
+    "sync"
+    "sync/atomic"
+)
+
+// Calificación de un usuario a un ítem
+type Rating struct {
+    User, Item string
+    Value      float64
+}
+
+// División de las calificaciones en entrenamiento y prueba. Train conserva
+// los registros originales, para cargarlos con AddRatingSequencial o
+// LoadRatingsConcurrent; Items es el número de ítems distintos en Train.
+type Split struct {
+    Train [][]string
+    Test  []Rating
+    Items int
+}
+
+// Registros de un usuario, por su posición en la entrada
+type userRecords struct {
+    user string
+    rows []int
+}
+
+// Interpretar los registros y agruparlos por usuario, en orden de nombre
+func groupRecords(records [][]string, parse RecordParser) ([]Rating, []userRecords, error) {
+    if parse == nil {
+        parse = ParseRatingRecord
+    }
+    parsed := make([]Rating, len(records))
+    byUser := make(map[string][]int)
+    for i, record := range records {
+        user, item, rating, err := parse(record)
+        if err != nil {
+            return nil, nil, fmt.Errorf("fila %d: %w", i, err)
+        }
+        parsed[i] = Rating{User: user, Item: item, Value: rating}
+        byUser[user] = append(byUser[user], i)
+    }
+    groups := make([]userRecords, 0, len(byUser))
+    for user, rows := range byUser {
+        groups = append(groups, userRecords{user: user, rows: rows})
+    }
+    sort.Slice(groups, func(i, j int) bool { return groups[i].user < groups[j].user })
+    return parsed, groups, nil
+}
+
+// Construir la división a partir de las filas marcadas como prueba
+func newSplit(records [][]string, parsed []Rating, test []bool) Split {
+    var split Split
+    items := make(map[string]struct{})
+    for i, record := range records {
+        if test[i] {
+            split.Test = append(split.Test, parsed[i])
+            continue
+        }
+        split.Train = append(split.Train, record)
+        items[parsed[i].Item] = struct{}{}
+    }
+    split.Items = len(items)
+    return split
+}
+
+// Dejar fuera, para prueba, una calificación elegida al azar de cada usuario
+// con al menos dos. parse interpreta cada registro y por defecto es
+// ParseRatingRecord.
+func LeaveOneOutSplit(records [][]string, parse RecordParser, seed int64) (Split, error) {
+    parsed, groups, err := groupRecords(records, parse)
+    if err != nil {
+        return Split{}, err
+    }
+    rng := rand.New(rand.NewSource(seed))
+    test := make([]bool, len(records))
+    for _, g := range groups {
+        if len(g.rows) >= 2 {
+            test[g.rows[rng.Intn(len(g.rows))]] = true
+        }
+    }
+    return newSplit(records, parsed, test), nil
+}
+
+// Dejar fuera, para prueba, la fracción testFraction más reciente de las
+// calificaciones de cada usuario con al menos dos, según la cuarta columna
+// (timestamp) de los registros. Cada usuario conserva al menos una
+// calificación en entrenamiento y aporta al menos una a prueba.
+func TemporalSplit(records [][]string, parse RecordParser, testFraction float64) (Split, error) {
+    if testFraction <= 0 || testFraction >= 1 {
+        return Split{}, fmt.Errorf("la fracción de prueba debe estar entre 0 y 1, es %v", testFraction)
+    }
+    parsed, groups, err := groupRecords(records, parse)
+    if err != nil {
+        return Split{}, err
+    }
+    timestamps := make([]int64, len(records))
+    for i, record := range records {
+        if len(record) < 4 {
+            return Split{}, fmt.Errorf("fila %d: falta la columna timestamp", i)
+        }
+        timestamps[i], err = strconv.ParseInt(record[3], 10, 64)
+        if err != nil {
+            return Split{}, fmt.Errorf("fila %d: error al convertir el timestamp '%s': %w", i, record[3], err)
+        }
+    }
+    test := make([]bool, len(records))
+    for _, g := range groups {
+        if len(g.rows) < 2 {
+            continue
+        }
+        sort.SliceStable(g.rows, func(a, b int) bool { return timestamps[g.rows[a]] < timestamps[g.rows[b]] })
+        held := int(math.Round(testFraction * float64(len(g.rows))))
+        held = min(max(held, 1), len(g.rows)-1)
+        for _, row := range g.rows[len(g.rows)-held:] {
+            test[row] = true
+        }
+    }
+    return newSplit(records, parsed, test), nil
+}
+
+// Estimador de calificaciones que se evalúa, por ejemplo un cierre sobre
+// PredictRatingConcurrent
+type Predictor func(user, item string) Prediction
+
+// Generador de las k mejores recomendaciones de un usuario que se evalúa,
+// por ejemplo un cierre sobre RecommendConcurrent
+type Ranker func(user string, k int) []string
+
+// Configuración de la evaluación fuera de línea. Los valores a cero toman
+// los valores por defecto indicados.
+type Evaluation struct {
+    K         int     // longitud de las listas de recomendación, por defecto 10
+    Relevance float64 // calificación mínima de prueba que cuenta como relevante; 0 las cuenta todas
+    Workers   int     // goroutines, por defecto tantas como CPUs
+}
+
+// Resultado de una evaluación. RMSE y MAE se calculan sobre las
+// Predictions calificaciones de prueba, de las que Predicted tuvieron algún
+// vecino. Las métricas de ranking (@K) se promedian sobre los Users
+// usuarios con algún ítem relevante en prueba; Coverage es la fracción de
+// los ítems de entrenamiento que aparece en alguna recomendación.
+type EvaluationMetrics struct {
+    Predictions int
+    Predicted   int
+    RMSE        float64
+    MAE         float64
+    Users       int
+    K           int
+    Precision   float64
+    Recall      float64
+    NDCG        float64
+    MAP         float64
+    Coverage    float64
+}
+
+func (m EvaluationMetrics) String() string {
+    return fmt.Sprintf("RMSE %.4f  MAE %.4f  (%d/%d con vecinos)\n"+
+        "P@%d %.4f  R@%d %.4f  NDCG@%d %.4f  MAP %.4f  cobertura %.4f  (%d usuarios)",
+        m.RMSE, m.MAE, m.Predicted, m.Predictions,
+        m.K, m.Precision, m.K, m.Recall, m.K, m.NDCG, m.MAP, m.Coverage, m.Users)
+}
+
+// Sumas parciales de cada worker
+type evaluationSums struct {
+    predictions, predicted, users             int
+    squaredError, absoluteError               float64
+    precision, recall, ndcg, averagePrecision float64
+    recommended                               map[string]struct{}
+}
+
+// Evaluar un recomendador sobre la parte de prueba de split. Los usuarios
+// de prueba se reparten entre workers goroutines; predict y rank pueden ser
+// nil para omitir las métricas de error o las de ranking, respectivamente.
+func (e Evaluation) Run(split Split, predict Predictor, rank Ranker) EvaluationMetrics {
+    k := e.K
+    if k <= 0 {
+        k = 10
+    }
+    workers := e.Workers
+    if workers <= 0 {
+        workers = runtime.NumCPU()
+    }
+
+    byUser := make(map[string][]Rating)
+    for _, r := range split.Test {
+        byUser[r.User] = append(byUser[r.User], r)
+    }
+    users := make([]string, 0, len(byUser))
+    for user := range byUser {
+        users = append(users, user)
+    }
+
+    partial := make([]evaluationSums, min(workers, len(users)))
+    var next atomic.Int64
+    var wg sync.WaitGroup
+    for w := range partial {
+        wg.Add(1)
+        go func(sums *evaluationSums) {
+            defer wg.Done()
+            sums.recommended = make(map[string]struct{})
+            for {
+                u := int(next.Add(1)) - 1
+                if u >= len(users) {
+                    return
+                }
+                test := byUser[users[u]]
+                if predict != nil {
+                    for _, r := range test {
+                        p := predict(r.User, r.Item)
+                        diff := p.Rating - r.Value
+                        sums.squaredError += diff * diff
+                        sums.absoluteError += math.Abs(diff)
+                        sums.predictions++
+                        if p.Known() {
+                            sums.predicted++
+                        }
+                    }
+                }
+                if rank != nil {
+                    sums.addRanking(test, rank(users[u], k), k, e.Relevance)
+                }
+            }
+        }(&partial[w])
+    }
+    wg.Wait()
+
+    var total evaluationSums
+    recommended := make(map[string]struct{})
+    for _, sums := range partial {
+        total.predictions += sums.predictions
+        total.predicted += sums.predicted
+        total.users += sums.users
+        total.squaredError += sums.squaredError
+        total.absoluteError += sums.absoluteError
+        total.precision += sums.precision
+        total.recall += sums.recall
+        total.ndcg += sums.ndcg
+        total.averagePrecision += sums.averagePrecision
+        for item := range sums.recommended {
+            recommended[item] = struct{}{}
+        }
+    }
+    users64 := float64(total.users)
+    return EvaluationMetrics{
+        Predictions: total.predictions,
+        Predicted:   total.predicted,
+        RMSE:        math.Sqrt(safeRatio(total.squaredError, float64(total.predictions))),
+        MAE:         safeRatio(total.absoluteError, float64(total.predictions)),
+        Users:       total.users,
+        K:           k,
+        Precision:   safeRatio(total.precision, users64),
+        Recall:      safeRatio(total.recall, users64),
+        NDCG:        safeRatio(total.ndcg, users64),
+        MAP:         safeRatio(total.averagePrecision, users64),
+        Coverage:    safeRatio(float64(len(recommended)), float64(split.Items)),
+    }
+}
+
+// Acumular las métricas de ranking de la lista recommended de un usuario
+// frente a sus calificaciones de prueba
+func (s *evaluationSums) addRanking(test []Rating, recommended []string, k int, relevance float64) {
+    for _, item := range recommended {
+        s.recommended[item] = struct{}{}
+    }
+    relevant := make(map[string]struct{})
+    for _, r := range test {
+        if r.Value >= relevance {
+            relevant[r.Item] = struct{}{}
+        }
+    }
+    if len(relevant) == 0 {
+        return
+    }
+    s.users++
+
+    var hits int
+    var dcg, precisionSum float64
+    for pos, item := range recommended[:min(k, len(recommended))] {
+        if _, ok := relevant[item]; !ok {
+            continue
+        }
+        hits++
+        dcg += 1 / math.Log2(float64(pos+2))
+        precisionSum += float64(hits) / float64(pos+1)
+    }
+    var idcg float64
+    for pos := 0; pos < min(k, len(relevant)); pos++ {
+        idcg += 1 / math.Log2(float64(pos+2))
+    }
+    s.precision += float64(hits) / float64(k)
+    s.recall += float64(hits) / float64(len(relevant))
+    s.ndcg += dcg / idcg
+    s.averagePrecision += precisionSum / float64(min(k, len(relevant)))
+}
@@ -0,0 +1,234 @@
+package fc
+
+import (
+    "math/rand"
+    "runtime"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+// Recomendador de factores latentes: cada usuario u y cada ítem i se
+// representan con vectores p_u y q_i de Factors componentes, guardados como
+// filas de matrices densas, y la calificación se estima como
+// μ + b_u + b_i + p_u · q_i, con μ la media global. Se entrena con
+// TrainALSConcurrent (sin sesgos) o con TrainSGDSequencial (SVD con
+// sesgos). Los valores a cero toman los valores por defecto indicados.
+type MatrixFactorization struct {
+    Factors      int     // dimensión latente, por defecto 10
+    Iterations   int     // iteraciones de ALS o épocas de SGD, por defecto 10
+    Lambda       float64 // regularización, por defecto 0.1 en ALS y 0.02 en SGD
+    LearningRate float64 // paso de SGD, por defecto 0.005
+    Workers      int     // goroutines de ALS, por defecto tantas como CPUs
+    Seed         int64   // semilla de la inicialización y del orden de SGD
+
+    ratings     *RatingMatrix
+    userFactors *DenseMatrix
+    itemFactors *DenseMatrix
+    userBias    []float64
+    itemBias    []float64
+    globalMean  float64
+    seed        int64
+}
+
+// Pasar las calificaciones a una RatingMatrix, cuyos identificadores
+// indexan las filas de los factores, e inicializar el modelo
+func (mf *MatrixFactorization) prepare(ratings RatingStore) {
+    if mf.Factors <= 0 {
+        mf.Factors = 10
+    }
+    if mf.Iterations <= 0 {
+        mf.Iterations = 10
+    }
+    mf.seed = mf.Seed
+    if mf.seed == 0 {
+        mf.seed = time.Now().UnixNano()
+    }
+
+    mf.ratings = NewRatingMatrix(ratings)
+    numUsers, numItems := mf.ratings.users.Len(), mf.ratings.items.Len()
+    var sum float64
+    for _, value := range mf.ratings.rowValues {
+        sum += float64(value)
+    }
+    mf.globalMean = safeRatio(sum, float64(mf.ratings.NumRatings()))
+
+    rng := rand.New(rand.NewSource(mf.seed))
+    mf.userFactors = RandomDenseMatrix(numUsers, mf.Factors, 0.1, rng)
+    mf.itemFactors = RandomDenseMatrix(numItems, mf.Factors, 0.1, rng)
+    mf.userBias = make([]float64, numUsers)
+    mf.itemBias = make([]float64, numItems)
+}
+
+// Entrenar con mínimos cuadrados alternados. En cada iteración se fijan los
+// factores de los ítems y se resuelve, para cada usuario, el sistema
+// (Qᵤᵀ Qᵤ + λ nᵤ I) pᵤ = Qᵤᵀ (rᵤ - μ) sobre los ítems que calificó; luego se
+// hace lo mismo para los ítems con los usuarios fijos. Los sistemas son
+// independientes, así que los usuarios (y después los ítems) se reparten
+// entre Workers goroutines.
+func (mf *MatrixFactorization) TrainALSConcurrent(ratings RatingStore) {
+    mf.prepare(ratings)
+    lambda := mf.Lambda
+    if lambda == 0 {
+        lambda = 0.1
+    }
+    workers := mf.Workers
+    if workers <= 0 {
+        workers = runtime.NumCPU()
+    }
+
+    m := mf.ratings
+    for it := 0; it < mf.Iterations; it++ {
+        mf.solveALS(mf.userFactors, mf.itemFactors, m.UserRow, lambda, workers)
+        mf.solveALS(mf.itemFactors, mf.userFactors, m.ItemColumn, lambda, workers)
+    }
+}
+
+// Resolver por mínimos cuadrados regularizados cada fila de target con las
+// filas de fixed como regresores. ratingsOf devuelve, para cada fila de
+// target, las filas de fixed con calificación y sus valores: una fila de
+// la vista CSR o una columna de la CSC.
+func (mf *MatrixFactorization) solveALS(target, fixed *DenseMatrix, ratingsOf func(int32) ([]int32, []float32), lambda float64, workers int) {
+    var next atomic.Int64
+    var wg sync.WaitGroup
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            a := NewDenseMatrix(mf.Factors, mf.Factors)
+            b := make([]float64, mf.Factors)
+            for {
+                r := int(next.Add(1)) - 1
+                if r >= target.Rows() {
+                    return
+                }
+                others, values := ratingsOf(int32(r))
+                if len(others) == 0 {
+                    continue
+                }
+                a.Zero()
+                clear(b)
+                for x, other := range others {
+                    q := fixed.Row(int(other))
+                    a.AddOuter(1, q, q)
+                    residual := float64(values[x]) - mf.globalMean
+                    for f := range b {
+                        b[f] += residual * q[f]
+                    }
+                }
+                a.AddDiagonal(lambda * float64(len(others)))
+                if err := a.Cholesky(); err != nil {
+                    continue
+                }
+                a.CholeskySolve(b)
+                copy(target.Row(r), b)
+            }
+        }()
+    }
+    wg.Wait()
+}
+
+// Entrenar una SVD con sesgos por descenso de gradiente estocástico,
+// recorriendo las calificaciones en orden aleatorio en cada época
+func (mf *MatrixFactorization) TrainSGDSequencial(ratings RatingStore) {
+    mf.prepare(ratings)
+    lambda := mf.Lambda
+    if lambda == 0 {
+        lambda = 0.02
+    }
+    rate := mf.LearningRate
+    if rate == 0 {
+        rate = 0.005
+    }
+
+    entries := make([]matrixEntry, 0, mf.ratings.NumRatings())
+    for u := range mf.ratings.users.Len() {
+        items, values := mf.ratings.UserRow(int32(u))
+        for x, i := range items {
+            entries = append(entries, matrixEntry{user: int32(u), item: i, rating: values[x]})
+        }
+    }
+
+    rng := rand.New(rand.NewSource(mf.seed + 1))
+    for epoch := 0; epoch < mf.Iterations; epoch++ {
+        rng.Shuffle(len(entries), func(i, j int) { entries[i], entries[j] = entries[j], entries[i] })
+        for _, e := range entries {
+            p, q := mf.userFactors.Row(int(e.user)), mf.itemFactors.Row(int(e.item))
+            err := float64(e.rating) - (mf.globalMean + mf.userBias[e.user] + mf.itemBias[e.item] + Dot(p, q))
+            mf.userBias[e.user] += rate * (err - lambda*mf.userBias[e.user])
+            mf.itemBias[e.item] += rate * (err - lambda*mf.itemBias[e.item])
+            for f := range p {
+                pf, qf := p[f], q[f]
+                p[f] += rate * (err*qf - lambda*pf)
+                q[f] += rate * (err*pf - lambda*qf)
+            }
+        }
+    }
+}
+
+// Estimar la calificación de un usuario a un ítem. Si alguno de los dos no
+// estaba en el entrenamiento se usa la parte conocida de la estimación (la
+// media global más el sesgo disponible) y known es false.
+func (mf *MatrixFactorization) Predict(user, item string) (rating float64, known bool) {
+    if mf.ratings == nil {
+        return 0, false
+    }
+    u, userKnown := mf.ratings.users.ID(user)
+    i, itemKnown := mf.ratings.items.ID(item)
+    rating = mf.globalMean
+    if userKnown {
+        rating += mf.userBias[u]
+    }
+    if itemKnown {
+        rating += mf.itemBias[i]
+    }
+    if userKnown && itemKnown {
+        rating += Dot(mf.userFactors.Row(int(u)), mf.itemFactors.Row(int(i)))
+    }
+    return rating, userKnown && itemKnown
+}
+
+// Recomendar los k ítems con mayor calificación estimada, sin incluir los de
+// rated. Un modelo sin entrenar no da recomendaciones.
+func (mf *MatrixFactorization) Recommend(user string, rated map[string]float64, k int) []string {
+    if mf.ratings == nil {
+        return []string{}
+    }
+    scores := make(map[string]float64)
+    for _, item := range mf.ratings.items.Names() {
+        if _, exists := rated[item]; exists {
+            continue
+        }
+        scores[item], _ = mf.Predict(user, item)
+    }
+    return topK(scores, k)
+}
+
+// Factores de los usuarios, una fila por usuario en el orden de Users
+func (mf *MatrixFactorization) UserFactors() *DenseMatrix {
+    return mf.userFactors
+}
+
+// Factores de los ítems, una fila por ítem en el orden de Items
+func (mf *MatrixFactorization) ItemFactors() *DenseMatrix {
+    return mf.itemFactors
+}
+
+// Usuarios del entrenamiento, en el orden de los identificadores de la
+// matriz de calificaciones, que es el de sus nombres; nil si el modelo no
+// está entrenado
+func (mf *MatrixFactorization) Users() []string {
+    if mf.ratings == nil {
+        return nil
+    }
+    return mf.ratings.users.Names()
+}
+
+// Ítems del entrenamiento, en el orden de los identificadores de la matriz
+// de calificaciones; nil si el modelo no está entrenado
+func (mf *MatrixFactorization) Items() []string {
+    if mf.ratings == nil {
+        return nil
+    }
+    return mf.ratings.items.Names()
+}
@@ -0,0 +1,282 @@
+package fc
+
+import (
+    "math"
+    "math/rand"
+    "runtime"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+// Número de candados de la tabla de ítems en BPR
+const bprItemLocks = 1024
+
+// Recomendador de factores latentes para datos implícitos: clics,
+// visualizaciones o compras en lugar de calificaciones. Cada valor
+// guardado se interpreta como la intensidad de una interacción, no como
+// una calificación, y los pares sin valor como ausencia de interacción. El
+// modelo puntúa un ítem para un usuario como p_u · q_i (+ b_i con BPR) y
+// solo sirve para ordenar ítems, no para estimar calificaciones. Se
+// entrena con TrainWALSConcurrent o TrainBPRConcurrent. Los valores a cero
+// toman los valores por defecto indicados.
+type ImplicitFactorization struct {
+    Factors      int     // dimensión latente, por defecto 10
+    Iterations   int     // iteraciones de ALS o épocas de BPR, por defecto 15
+    Lambda       float64 // regularización, por defecto 0.1 en ALS y 0.01 en BPR
+    Alpha        float64 // peso de la confianza en ALS, por defecto 40
+    LearningRate float64 // paso de BPR, por defecto 0.05
+    Workers      int     // goroutines, por defecto tantas como CPUs
+    Seed         int64   // semilla de la inicialización y del muestreo
+
+    ratings     *RatingMatrix
+    userFactors *DenseMatrix
+    itemFactors *DenseMatrix
+    itemBias    []float64
+    seed        int64
+    workers     int
+}
+
+// Pasar las interacciones a una RatingMatrix e inicializar el modelo
+func (m *ImplicitFactorization) prepare(ratings RatingStore) {
+    if m.Factors <= 0 {
+        m.Factors = 10
+    }
+    if m.Iterations <= 0 {
+        m.Iterations = 15
+    }
+    m.workers = m.Workers
+    if m.workers <= 0 {
+        m.workers = runtime.NumCPU()
+    }
+    m.seed = m.Seed
+    if m.seed == 0 {
+        m.seed = time.Now().UnixNano()
+    }
+
+    m.ratings = NewRatingMatrix(ratings)
+    rng := rand.New(rand.NewSource(m.seed))
+    m.userFactors = RandomDenseMatrix(m.ratings.users.Len(), m.Factors, 0.1, rng)
+    m.itemFactors = RandomDenseMatrix(m.ratings.items.Len(), m.Factors, 0.1, rng)
+    m.itemBias = make([]float64, m.ratings.items.Len())
+}
+
+// Entrenar con mínimos cuadrados alternados ponderados por confianza (Hu,
+// Koren y Volinsky, 2008). Toda pareja usuario-ítem tiene preferencia 1 si
+// hubo interacción y 0 si no, con confianza c = 1 + Alpha · r. Para cada
+// usuario se resuelve (QᵀQ + Qᵀ(Cᵤ - I)Q + λI) pᵤ = Qᵀ Cᵤ 1ᵤ: QᵀQ se calcula
+// una vez por iteración y el resto solo recorre los ítems con interacción,
+// así que el coste no depende del número de parejas sin ella. Luego se
+// hace lo mismo para los ítems. Los sistemas se reparten entre Workers
+// goroutines.
+func (m *ImplicitFactorization) TrainWALSConcurrent(ratings RatingStore) {
+    m.prepare(ratings)
+    lambda := m.Lambda
+    if lambda == 0 {
+        lambda = 0.1
+    }
+    alpha := m.Alpha
+    if alpha == 0 {
+        alpha = 40
+    }
+    for it := 0; it < m.Iterations; it++ {
+        m.solveWALS(m.userFactors, m.itemFactors, m.ratings.UserRow, alpha, lambda)
+        m.solveWALS(m.itemFactors, m.userFactors, m.ratings.ItemColumn, alpha, lambda)
+    }
+}
+
+// Resolver cada fila de target con las filas de fixed como regresores.
+// interactionsOf devuelve, para cada fila de target, las filas de fixed
+// con interacción y su intensidad.
+func (m *ImplicitFactorization) solveWALS(target, fixed *DenseMatrix, interactionsOf func(int32) ([]int32, []float32), alpha, lambda float64) {
+    gram := fixed.Transpose().Mul(fixed)
+    var next atomic.Int64
+    var wg sync.WaitGroup
+    for w := 0; w < m.workers; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            a := NewDenseMatrix(m.Factors, m.Factors)
+            b := make([]float64, m.Factors)
+            for {
+                r := int(next.Add(1)) - 1
+                if r >= target.Rows() {
+                    return
+                }
+                others, values := interactionsOf(int32(r))
+                if len(others) == 0 {
+                    // Sin interacciones la solución es el vector nulo
+                    clear(target.Row(r))
+                    continue
+                }
+                copy(a.Array(), gram.Array())
+                clear(b)
+                for x, other := range others {
+                    q := fixed.Row(int(other))
+                    confidence := 1 + alpha*float64(values[x])
+                    a.AddOuter(confidence-1, q, q)
+                    for f := range b {
+                        b[f] += confidence * q[f]
+                    }
+                }
+                a.AddDiagonal(lambda)
+                if err := a.Cholesky(); err != nil {
+                    continue
+                }
+                a.CholeskySolve(b)
+                copy(target.Row(r), b)
+            }
+        }()
+    }
+    wg.Wait()
+}
+
+// Entrenar con Bayesian Personalized Ranking (Rendle et al., 2009):
+// maximizar la probabilidad de que cada usuario prefiera un ítem con el que
+// interactuó, i, a uno con el que no, j, por descenso de gradiente
+// estocástico sobre ternas (u, i, j) muestreadas. Cada época toma tantas
+// muestras como interacciones, repartidas entre Workers goroutines. Cada
+// worker muestrea solo usuarios propios, así que sus factores no se
+// comparten, y los de los ítems se protegen con candados por partición.
+func (m *ImplicitFactorization) TrainBPRConcurrent(ratings RatingStore) {
+    m.prepare(ratings)
+    lambda := m.Lambda
+    if lambda == 0 {
+        lambda = 0.01
+    }
+    rate := m.LearningRate
+    if rate == 0 {
+        rate = 0.05
+    }
+
+    // Usuarios con alguna interacción, repartidos entre los workers
+    owned := make([][]int32, m.workers)
+    for u := 0; u < m.ratings.users.Len(); u++ {
+        if items, _ := m.ratings.UserRow(int32(u)); len(items) > 0 && len(items) < m.ratings.items.Len() {
+            owned[u%m.workers] = append(owned[u%m.workers], int32(u))
+        }
+    }
+    var locks [bprItemLocks]sync.Mutex
+    samplesPerWorker := m.ratings.NumRatings()/m.workers + 1
+
+    for epoch := 0; epoch < m.Iterations; epoch++ {
+        var wg sync.WaitGroup
+        for w := 0; w < m.workers; w++ {
+            if len(owned[w]) == 0 {
+                continue
+            }
+            wg.Add(1)
+            go func(w int) {
+                defer wg.Done()
+                rng := rand.New(rand.NewSource(m.seed + int64(epoch*m.workers+w) + 1))
+                users := owned[w]
+                diff := make([]float64, m.Factors)
+                for s := 0; s < samplesPerWorker; s++ {
+                    u := users[rng.Intn(len(users))]
+                    items, _ := m.ratings.UserRow(u)
+                    i := items[rng.Intn(len(items))]
+                    j := int32(rng.Intn(m.ratings.items.Len()))
+                    for _, seen := m.ratings.rating(u, j); seen; _, seen = m.ratings.rating(u, j) {
+                        j = int32(rng.Intn(m.ratings.items.Len()))
+                    }
+                    m.bprStep(u, i, j, &locks, diff, rate, lambda)
+                }
+            }(w)
+        }
+        wg.Wait()
+    }
+}
+
+// Actualizar los factores con la terna (u, i, j), tomando los candados de
+// i y j en orden para no bloquearse con otro worker
+func (m *ImplicitFactorization) bprStep(u, i, j int32, locks *[bprItemLocks]sync.Mutex, diff []float64, rate, lambda float64) {
+    first, second := i%bprItemLocks, j%bprItemLocks
+    if first > second {
+        first, second = second, first
+    }
+    locks[first].Lock()
+    defer locks[first].Unlock()
+    if second != first {
+        locks[second].Lock()
+        defer locks[second].Unlock()
+    }
+
+    p := m.userFactors.Row(int(u))
+    qi, qj := m.itemFactors.Row(int(i)), m.itemFactors.Row(int(j))
+    for f := range diff {
+        diff[f] = qi[f] - qj[f]
+    }
+    x := m.itemBias[i] - m.itemBias[j] + Dot(p, diff)
+    // Derivada de ln σ(x)
+    g := 1 / (1 + math.Exp(x))
+    for f := range p {
+        pf := p[f]
+        p[f] += rate * (g*diff[f] - lambda*pf)
+        qi[f] += rate * (g*pf - lambda*qi[f])
+        qj[f] += rate * (-g*pf - lambda*qj[f])
+    }
+    m.itemBias[i] += rate * (g - lambda*m.itemBias[i])
+    m.itemBias[j] += rate * (-g - lambda*m.itemBias[j])
+}
+
+// Puntuación de un ítem para un usuario, o false si alguno de los dos no
+// estaba en el entrenamiento
+func (m *ImplicitFactorization) Score(user, item string) (float64, bool) {
+    if m.ratings == nil {
+        return 0, false
+    }
+    u, userKnown := m.ratings.users.ID(user)
+    i, itemKnown := m.ratings.items.ID(item)
+    if !userKnown || !itemKnown {
+        return 0, false
+    }
+    return m.itemBias[i] + Dot(m.userFactors.Row(int(u)), m.itemFactors.Row(int(i))), true
+}
+
+// Recomendar los k ítems de mayor puntuación con los que el usuario no
+// interactuó, ni en el entrenamiento ni en rated. Un usuario desconocido no
+// recibe recomendaciones; para él conviene usar un Fallback.
+func (m *ImplicitFactorization) Recommend(user string, rated map[string]float64, k int) []string {
+    if m.ratings == nil || k <= 0 {
+        return []string{}
+    }
+    u, exists := m.ratings.users.ID(user)
+    if !exists {
+        return []string{}
+    }
+    seen := make([]bool, m.ratings.items.Len())
+    items, _ := m.ratings.UserRow(u)
+    for _, i := range items {
+        seen[i] = true
+    }
+
+    p := m.userFactors.Row(int(u))
+    h := make(scoreHeap, 0, k+1)
+    for i := range seen {
+        if seen[i] {
+            continue
+        }
+        item := m.ratings.items.Name(int32(i))
+        if _, exists := rated[item]; exists {
+            continue
+        }
+        score := m.itemBias[i] + Dot(p, m.itemFactors.Row(i))
+        h.offer(scoredItem{item: item, id: i, score: score}, k)
+    }
+    ranked := h.drain()
+    recommendations := make([]string, len(ranked))
+    for x, r := range ranked {
+        recommendations[x] = r.item
+    }
+    return recommendations
+}
+
+// Factores de los usuarios, una fila por identificador de usuario
+func (m *ImplicitFactorization) UserFactors() *DenseMatrix {
+    return m.userFactors
+}
+
+// Factores de los ítems, una fila por identificador de ítem
+func (m *ImplicitFactorization) ItemFactors() *DenseMatrix {
+    return m.itemFactors
+}
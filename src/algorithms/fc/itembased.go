@@ -0,0 +1,264 @@
+package fc
+
+import (
+    "encoding/gob"
+    "fmt"
+    "os"
+    "runtime"
+    "sort"
+    "sync"
+    "sync/atomic"
+)
+
+// Almacén de calificaciones a partir del cual se entrenan los modelos.
+// RatingsSequencial y RatingsConcurrent lo implementan.
+type RatingStore interface {
+    EachUser(fn func(user string, ratings map[string]float64))
+}
+
+// Vecino de un ítem con su similitud
+type Neighbor struct {
+    Item       string
+    Similarity float64
+}
+
+// Tabla de vecinos más similares de cada ítem, en formato compacto: los
+// vecinos del ítem i ocupan las posiciones offsets[i] a offsets[i+1] de
+// neighbors y similarities, ordenados por similitud descendente. Solo se
+// guardan similitudes positivas.
+type ItemNeighbors struct {
+    items        []string
+    index        map[string]int
+    offsets      []int32
+    neighbors    []int32
+    similarities []float32
+}
+
+// Calcular los n vecinos más similares de cada ítem con workers goroutines.
+// Solo se comparan ítems que comparten algún usuario.
+func buildItemNeighbors(m *RatingMatrix, n int, similarity Similarity, workers int) *ItemNeighbors {
+    numItems := m.items.Len()
+    lists := make([][]scoredItem, numItems)
+    var next atomic.Int64
+    var wg sync.WaitGroup
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            // Marca de la última fila en la que se comparó cada ítem, para
+            // no compararlo dos veces sin usar un mapa por fila
+            seen := make([]int32, numItems)
+            for j := range seen {
+                seen[j] = -1
+            }
+            h := make(scoreHeap, 0, n+1)
+            c := CoRatings{Offsets: []float64{}}
+            for {
+                i := int(next.Add(1)) - 1
+                if i >= numItems {
+                    return
+                }
+                seen[i] = int32(i)
+                raters, _ := m.ItemColumn(int32(i))
+                for _, u := range raters {
+                    items, _ := m.UserRow(u)
+                    for _, j := range items {
+                        if seen[j] == int32(i) {
+                            continue
+                        }
+                        seen[j] = int32(i)
+                        m.itemCoRatings(&c, int32(i), j)
+                        if s := similarity.Similarity(&c); s > 0 {
+                            h.offer(scoredItem{item: m.items.Name(j), id: int(j), score: s}, n)
+                        }
+                    }
+                }
+                lists[i] = h.drain()
+            }
+        }()
+    }
+    wg.Wait()
+
+    nb := &ItemNeighbors{items: m.items.Names(), offsets: make([]int32, numItems+1)}
+    for i, list := range lists {
+        nb.offsets[i+1] = nb.offsets[i] + int32(len(list))
+    }
+    nb.neighbors = make([]int32, nb.offsets[numItems])
+    nb.similarities = make([]float32, nb.offsets[numItems])
+    for i, list := range lists {
+        for x, neighbor := range list {
+            nb.neighbors[int(nb.offsets[i])+x] = int32(neighbor.id)
+            nb.similarities[int(nb.offsets[i])+x] = float32(neighbor.score)
+        }
+    }
+    nb.buildIndex()
+    return nb
+}
+
+// Calcular la tabla de vecinos de cada ítem de forma secuencial
+func BuildItemNeighborsSequencial(ratings *RatingsSequencial, n int, similarity Similarity) *ItemNeighbors {
+    return buildItemNeighbors(NewRatingMatrix(ratings), n, similarity, 1)
+}
+
+// Calcular la tabla de vecinos de cada ítem repartiendo los ítems entre
+// workers goroutines (por defecto, tantas como CPUs)
+func BuildItemNeighborsConcurrent(ratings *RatingsConcurrent, n int, similarity Similarity, workers int) *ItemNeighbors {
+    if workers <= 0 {
+        workers = runtime.NumCPU()
+    }
+    return buildItemNeighbors(NewRatingMatrix(ratings), n, similarity, workers)
+}
+
+// Calcular la tabla de vecinos de cada ítem de una matriz de
+// calificaciones repartiendo los ítems entre workers goroutines (por
+// defecto, tantas como CPUs)
+func BuildItemNeighborsCSR(m *RatingMatrix, n int, similarity Similarity, workers int) *ItemNeighbors {
+    if workers <= 0 {
+        workers = runtime.NumCPU()
+    }
+    return buildItemNeighbors(m, n, similarity, workers)
+}
+
+func (nb *ItemNeighbors) buildIndex() {
+    nb.index = make(map[string]int, len(nb.items))
+    for i, item := range nb.items {
+        nb.index[item] = i
+    }
+}
+
+// Número de ítems de la tabla
+func (nb *ItemNeighbors) Len() int {
+    return len(nb.items)
+}
+
+// Obtener los k ítems más similares a item, de mayor a menor similitud
+func (nb *ItemNeighbors) SimilarItems(item string, k int) []Neighbor {
+    i, exists := nb.index[item]
+    if !exists {
+        return nil
+    }
+    start, end := int(nb.offsets[i]), int(nb.offsets[i+1])
+    end = min(end, start+max(k, 0))
+    similar := make([]Neighbor, 0, end-start)
+    for x := start; x < end; x++ {
+        similar = append(similar, Neighbor{Item: nb.items[nb.neighbors[x]], Similarity: float64(nb.similarities[x])})
+    }
+    return similar
+}
+
+// Recomendar los k ítems no calificados con mayor puntuación estimada, que
+// es la media de las calificaciones del usuario ponderada por la similitud
+// de cada ítem calificado con el candidato
+func (nb *ItemNeighbors) Recommend(userRatings map[string]float64, k int) []string {
+    // Los ítems calificados se recorren en orden para que las sumas, y con
+    // ellas el ranking, no dependan del orden del mapa
+    rated := make([]string, 0, len(userRatings))
+    for item := range userRatings {
+        rated = append(rated, item)
+    }
+    sort.Strings(rated)
+
+    scores := make(map[string]float64)
+    similaritySums := make(map[string]float64)
+    for _, item := range rated {
+        rating := userRatings[item]
+        i, exists := nb.index[item]
+        if !exists {
+            continue
+        }
+        for x := nb.offsets[i]; x < nb.offsets[i+1]; x++ {
+            neighbor := nb.items[nb.neighbors[x]]
+            if _, rated := userRatings[neighbor]; rated {
+                continue
+            }
+            similarity := float64(nb.similarities[x])
+            scores[neighbor] += similarity * rating
+            similaritySums[neighbor] += similarity
+        }
+    }
+    for item := range scores {
+        scores[item] /= similaritySums[item]
+    }
+    return topK(scores, k)
+}
+
+// Estimar la calificación de un usuario, dada por sus calificaciones, a un
+// ítem: media de lo que calificó entre los vecinos del ítem, ponderada por
+// su similitud con él
+func (nb *ItemNeighbors) PredictRating(userRatings map[string]float64, item string) Prediction {
+    var sum predictionSum
+    if i, exists := nb.index[item]; exists {
+        for x := nb.offsets[i]; x < nb.offsets[i+1]; x++ {
+            if rating, rated := userRatings[nb.items[nb.neighbors[x]]]; rated {
+                sum.add(float64(nb.similarities[x]), rating)
+            }
+        }
+    }
+    return sum.prediction(NoNormalization, statsOf(userRatings))
+}
+
+// Generar recomendaciones basadas en ítems para un usuario
+func RecommendItemBasedSequencial(nb *ItemNeighbors, ratings *RatingsSequencial, user string, k int) []string {
+    return nb.Recommend(ratings.UserRatingsSequencial(user), k)
+}
+
+// Generar recomendaciones basadas en ítems para un usuario, leyendo sus
+// calificaciones de forma segura mientras el almacén se actualiza
+func RecommendItemBasedConcurrent(nb *ItemNeighbors, ratings *RatingsConcurrent, user string, k int) []string {
+    return nb.Recommend(ratings.UserRatingsConcurrent(user), k)
+}
+
+// Formato en disco de la tabla de vecinos
+type itemNeighborsFile struct {
+    Items        []string
+    Offsets      []int32
+    Neighbors    []int32
+    Similarities []float32
+}
+
+// Guardar la tabla de vecinos en path
+func (nb *ItemNeighbors) Save(path string) error {
+    file, err := os.Create(path)
+    if err != nil {
+        return err
+    }
+    defer file.Close()
+    err = gob.NewEncoder(file).Encode(itemNeighborsFile{
+        Items:        nb.items,
+        Offsets:      nb.offsets,
+        Neighbors:    nb.neighbors,
+        Similarities: nb.similarities,
+    })
+    if err != nil {
+        return err
+    }
+    return file.Close()
+}
+
+// Cargar una tabla de vecinos guardada con Save
+func LoadItemNeighbors(path string) (*ItemNeighbors, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+    var f itemNeighborsFile
+    if err := gob.NewDecoder(file).Decode(&f); err != nil {
+        return nil, err
+    }
+    if len(f.Offsets) == 0 {
+        f.Offsets = []int32{0}
+    }
+    if len(f.Offsets) != len(f.Items)+1 || len(f.Neighbors) != len(f.Similarities) ||
+        int(f.Offsets[len(f.Items)]) != len(f.Neighbors) {
+        return nil, fmt.Errorf("tabla de vecinos corrupta en %s", path)
+    }
+    nb := &ItemNeighbors{
+        items:        f.Items,
+        offsets:      f.Offsets,
+        neighbors:    f.Neighbors,
+        similarities: f.Similarities,
+    }
+    nb.buildIndex()
+    return nb, nil
+}
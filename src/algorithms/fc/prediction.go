@@ -0,0 +1,48 @@
+package fc
+
+// Calificación estimada de un usuario a un ítem. Rating es la media de las
+// calificaciones de los vecinos ponderada por su similitud, normalizadas y
+// devueltas a la escala del usuario si así se pide; Neighbors es el
+// número de vecinos con similitud positiva que intervinieron y
+// SimilaritySum la suma de sus similitudes. Confidence resume esa evidencia
+// en [0, 1) como SimilaritySum / (SimilaritySum + 1). Sin vecinos se
+// devuelve la media del usuario (0 si no tiene calificaciones) con
+// confianza 0.
+type Prediction struct {
+    Rating        float64
+    Neighbors     int
+    SimilaritySum float64
+    Confidence    float64
+}
+
+// Indica si la estimación se basa en algún vecino
+func (p Prediction) Known() bool {
+    return p.Neighbors > 0
+}
+
+// Acumulador de una estimación por vecinos
+type predictionSum struct {
+    weighted      float64
+    similaritySum float64
+    neighbors     int
+}
+
+func (s *predictionSum) add(similarity, rating float64) {
+    s.weighted += similarity * rating
+    s.similaritySum += similarity
+    s.neighbors++
+}
+
+// Cerrar la estimación, devolviéndola a la escala del usuario de
+// estadísticas stats, o su media si no hubo vecinos
+func (s *predictionSum) prediction(norm Normalization, stats ratingStats) Prediction {
+    if s.neighbors == 0 || s.similaritySum == 0 {
+        return Prediction{Rating: stats.mean}
+    }
+    return Prediction{
+        Rating:        norm.denormalize(s.weighted/s.similaritySum, stats),
+        Neighbors:     s.neighbors,
+        SimilaritySum: s.similaritySum,
+        Confidence:    s.similaritySum / (s.similaritySum + 1),
+    }
+}
@@ -0,0 +1,492 @@
+package fc
+
+import (
+    "fmt"
+    "math"
+    "runtime"
+    "sort"
+    "sync"
+)
+
+// Almacén compacto e inmutable de calificaciones. Usuarios e ítems se
+// guardan como identificadores int32 de dos diccionarios, y las
+// calificaciones, como float32, en dos matrices dispersas: por filas (CSR,
+// usuario × ítem) y por columnas (CSC, ítem × usuario). Las calificaciones
+// del usuario u ocupan las posiciones rowOffsets[u] a rowOffsets[u+1] de
+// rowItems y rowValues, ordenadas por ítem, y las del ítem i, las
+// posiciones colOffsets[i] a colOffsets[i+1] de colUsers y colValues,
+// ordenadas por usuario. Implementa RatingStore, así que puede usarse para
+// entrenar cualquiera de los modelos, y es la representación sobre la que
+// trabajan los recomendadores basados en usuarios: RatingsSequencial y
+// RatingsConcurrent mantienen una instantánea suya.
+type RatingMatrix struct {
+    users      *Dictionary
+    items      *Dictionary
+    rowOffsets []int32
+    rowItems   []int32
+    rowValues  []float32
+    colOffsets []int32
+    colUsers   []int32
+    colValues  []float32
+    userStats  []ratingStats
+    itemMeans  []float64
+}
+
+// Calificación con usuario e ítem ya internados
+type matrixEntry struct {
+    user, item int32
+    rating     float32
+}
+
+// Almacenes que mantienen su propia instantánea como RatingMatrix
+type matrixStore interface {
+    Matrix() *RatingMatrix
+}
+
+// Construir la matriz a partir de otro almacén, numerando usuarios e ítems
+// por orden de nombre para que el resultado no dependa del orden en que el
+// almacén los recorre. Si ratings ya es una RatingMatrix se devuelve tal
+// cual, y si mantiene una instantánea, esta.
+func NewRatingMatrix(ratings RatingStore) *RatingMatrix {
+    switch store := ratings.(type) {
+    case *RatingMatrix:
+        return store
+    case matrixStore:
+        return store.Matrix()
+    }
+    users, items := NewDictionary(), NewDictionary()
+    var entries []matrixEntry
+    ratings.EachUser(func(user string, userRatings map[string]float64) {
+        u := users.Intern(user)
+        for item, rating := range userRatings {
+            entries = append(entries, matrixEntry{user: u, item: items.Intern(item), rating: float32(rating)})
+        }
+    })
+    return newSortedRatingMatrix(users.Names(), items.Names(), entries)
+}
+
+// Construir la matriz a partir de registros de calificaciones, que se
+// interpretan en paralelo con parse (por defecto ParseRatingRecord).
+// Usuarios e ítems se numeran por orden de nombre, como en NewRatingMatrix,
+// y, si un par se repite, prevalece la última calificación. Se devuelve el
+// primer error encontrado, junto con la fila que lo produjo; las filas
+// válidas se incluyen igualmente.
+func LoadRatingMatrix(records [][]string, parse RecordParser) (*RatingMatrix, error) {
+    if parse == nil {
+        parse = ParseRatingRecord
+    }
+    parsed := make([]Rating, len(records))
+    valid := make([]bool, len(records))
+    workers := runtime.NumCPU()
+    chunkSize := (len(records) + workers - 1) / workers
+    var wg sync.WaitGroup
+    var once sync.Once
+    var firstErr error
+    for start := 0; start < len(records); start += chunkSize {
+        end := min(start+chunkSize, len(records))
+        wg.Add(1)
+        go func(start, end int) {
+            defer wg.Done()
+            for i := start; i < end; i++ {
+                user, item, rating, err := parse(records[i])
+                if err != nil {
+                    once.Do(func() { firstErr = fmt.Errorf("fila %d: %w", i, err) })
+                    continue
+                }
+                parsed[i] = Rating{User: user, Item: item, Value: rating}
+                valid[i] = true
+            }
+        }(start, end)
+    }
+    wg.Wait()
+
+    users, items := NewDictionary(), NewDictionary()
+    entries := make([]matrixEntry, 0, len(records))
+    for i, r := range parsed {
+        if valid[i] {
+            entries = append(entries, matrixEntry{user: users.Intern(r.User), item: items.Intern(r.Item), rating: float32(r.Value)})
+        }
+    }
+    return newSortedRatingMatrix(users.Names(), items.Names(), entries), firstErr
+}
+
+// Construir la matriz renumerando usuarios e ítems por orden de nombre, de
+// modo que no dependa del orden en que se internaron. userNames e itemNames
+// dan el nombre de cada identificador de entries; los que no aparecen en
+// ninguna calificación se descartan. entries se modifica.
+func newSortedRatingMatrix(userNames, itemNames []string, entries []matrixEntry) *RatingMatrix {
+    usedUsers := make([]bool, len(userNames))
+    usedItems := make([]bool, len(itemNames))
+    for _, e := range entries {
+        usedUsers[e.user] = true
+        usedItems[e.item] = true
+    }
+    users, userIDs := sortedDictionary(userNames, usedUsers)
+    items, itemIDs := sortedDictionary(itemNames, usedItems)
+    for x := range entries {
+        entries[x].user = userIDs[entries[x].user]
+        entries[x].item = itemIDs[entries[x].item]
+    }
+    return buildRatingMatrix(users, items, entries)
+}
+
+// Diccionario con los nombres usados, en orden alfabético, y el
+// identificador en él de cada posición de names
+func sortedDictionary(names []string, used []bool) (*Dictionary, []int32) {
+    order := make([]int32, 0, len(names))
+    for id := range names {
+        if used[id] {
+            order = append(order, int32(id))
+        }
+    }
+    sort.Slice(order, func(a, b int) bool { return names[order[a]] < names[order[b]] })
+    d := NewDictionary()
+    ids := make([]int32, len(names))
+    for _, id := range order {
+        ids[id] = d.Intern(names[id])
+    }
+    return d, ids
+}
+
+// Construir las vistas CSR y CSC y las estadísticas a partir de las
+// calificaciones, que se ordenan por usuario e ítem
+func buildRatingMatrix(users, items *Dictionary, entries []matrixEntry) *RatingMatrix {
+    sort.SliceStable(entries, func(a, b int) bool {
+        if entries[a].user != entries[b].user {
+            return entries[a].user < entries[b].user
+        }
+        return entries[a].item < entries[b].item
+    })
+    // Quitar pares repetidos conservando la última calificación
+    unique := entries[:0]
+    for _, e := range entries {
+        if n := len(unique); n > 0 && unique[n-1].user == e.user && unique[n-1].item == e.item {
+            unique[n-1] = e
+            continue
+        }
+        unique = append(unique, e)
+    }
+    entries = unique
+
+    m := &RatingMatrix{
+        users:      users,
+        items:      items,
+        rowOffsets: make([]int32, users.Len()+1),
+        rowItems:   make([]int32, len(entries)),
+        rowValues:  make([]float32, len(entries)),
+        colOffsets: make([]int32, items.Len()+1),
+        colUsers:   make([]int32, len(entries)),
+        colValues:  make([]float32, len(entries)),
+        userStats:  make([]ratingStats, users.Len()),
+        itemMeans:  make([]float64, items.Len()),
+    }
+    for x, e := range entries {
+        m.rowOffsets[e.user+1]++
+        m.colOffsets[e.item+1]++
+        m.rowItems[x] = e.item
+        m.rowValues[x] = e.rating
+    }
+    for u := 0; u < users.Len(); u++ {
+        m.rowOffsets[u+1] += m.rowOffsets[u]
+    }
+    for i := 0; i < items.Len(); i++ {
+        m.colOffsets[i+1] += m.colOffsets[i]
+    }
+    // Al recorrer las calificaciones por usuario, cada columna queda
+    // ordenada por usuario
+    next := make([]int32, items.Len())
+    copy(next, m.colOffsets)
+    for _, e := range entries {
+        m.colUsers[next[e.item]] = e.user
+        m.colValues[next[e.item]] = e.rating
+        next[e.item]++
+    }
+
+    for u := range m.userStats {
+        _, values := m.UserRow(int32(u))
+        m.userStats[u] = statsOfValues(values)
+    }
+    for i := range m.itemMeans {
+        _, values := m.ItemColumn(int32(i))
+        m.itemMeans[i] = statsOfValues(values).mean
+    }
+    return m
+}
+
+// Media y desviación típica de un vector de calificaciones
+func statsOfValues(values []float32) ratingStats {
+    if len(values) == 0 {
+        return ratingStats{}
+    }
+    var sum, sumSquares float64
+    for _, value := range values {
+        sum += float64(value)
+        sumSquares += float64(value) * float64(value)
+    }
+    n := float64(len(values))
+    mean := sum / n
+    return ratingStats{mean: mean, std: math.Sqrt(max(sumSquares/n-mean*mean, 0))}
+}
+
+// Diccionario de usuarios
+func (m *RatingMatrix) Users() *Dictionary {
+    return m.users
+}
+
+// Diccionario de ítems
+func (m *RatingMatrix) Items() *Dictionary {
+    return m.items
+}
+
+// Número de calificaciones
+func (m *RatingMatrix) NumRatings() int {
+    return len(m.rowItems)
+}
+
+// Ítems calificados por el usuario u, ordenados, y sus calificaciones. Los
+// slices son vistas sobre la matriz y no deben modificarse.
+func (m *RatingMatrix) UserRow(u int32) ([]int32, []float32) {
+    start, end := m.rowOffsets[u], m.rowOffsets[u+1]
+    return m.rowItems[start:end], m.rowValues[start:end]
+}
+
+// Usuarios que calificaron el ítem i, ordenados, y sus calificaciones. Los
+// slices son vistas sobre la matriz y no deben modificarse.
+func (m *RatingMatrix) ItemColumn(i int32) ([]int32, []float32) {
+    start, end := m.colOffsets[i], m.colOffsets[i+1]
+    return m.colUsers[start:end], m.colValues[start:end]
+}
+
+// Calificación del usuario u al ítem i, buscada en la fila del usuario
+func (m *RatingMatrix) rating(u, i int32) (float64, bool) {
+    items, values := m.UserRow(u)
+    x := sort.Search(len(items), func(x int) bool { return items[x] >= i })
+    if x < len(items) && items[x] == i {
+        return float64(values[x]), true
+    }
+    return 0, false
+}
+
+// Obtener la calificación de un usuario a un ítem
+func (m *RatingMatrix) Rating(user, item string) (float64, bool) {
+    u, userExists := m.users.ID(user)
+    i, itemExists := m.items.ID(item)
+    if !userExists || !itemExists {
+        return 0, false
+    }
+    return m.rating(u, i)
+}
+
+// Obtener una copia de las calificaciones de un usuario
+func (m *RatingMatrix) UserRatings(user string) map[string]float64 {
+    u, exists := m.users.ID(user)
+    if !exists {
+        return map[string]float64{}
+    }
+    items, values := m.UserRow(u)
+    ratings := make(map[string]float64, len(items))
+    for x, i := range items {
+        ratings[m.items.Name(i)] = float64(values[x])
+    }
+    return ratings
+}
+
+// Recorrer las calificaciones de cada usuario, en orden de identificador.
+// Cada mapa se construye en el momento; para recorrer la matriz sin
+// reservar memoria conviene usar UserRow.
+func (m *RatingMatrix) EachUser(fn func(user string, ratings map[string]float64)) {
+    for u, user := range m.users.Names() {
+        items, values := m.UserRow(int32(u))
+        ratings := make(map[string]float64, len(items))
+        for x, i := range items {
+            ratings[m.items.Name(i)] = float64(values[x])
+        }
+        fn(user, ratings)
+    }
+}
+
+// Calcular la similitud entre dos usuarios con la medida indicada. Un
+// usuario desconocido no tiene calificaciones en común con nadie.
+func (m *RatingMatrix) userSimilarity(similarity Similarity, user1, user2 string) float64 {
+    var c CoRatings
+    u, exists1 := m.users.ID(user1)
+    v, exists2 := m.users.ID(user2)
+    if exists1 && exists2 {
+        m.userCoRatings(&c, u, v)
+    }
+    return similarity.Similarity(&c)
+}
+
+// Llenar c con las calificaciones en común de los usuarios u y v, cruzando
+// sus filas ordenadas
+func (m *RatingMatrix) userCoRatings(c *CoRatings, u, v int32) {
+    c.reset()
+    c.Offsets = nil
+    a, aValues := m.UserRow(u)
+    b, bValues := m.UserRow(v)
+    c.CountA, c.CountB = len(a), len(b)
+    c.MeanA, c.MeanB = m.userStats[u].mean, m.userStats[v].mean
+    for x, y := 0, 0; x < len(a) && y < len(b); {
+        switch {
+        case a[x] < b[y]:
+            x++
+        case a[x] > b[y]:
+            y++
+        default:
+            c.A = append(c.A, float64(aValues[x]))
+            c.B = append(c.B, float64(bValues[y]))
+            x++
+            y++
+        }
+    }
+}
+
+// Llenar c con las calificaciones de los usuarios que calificaron los ítems
+// i y j, cruzando sus columnas ordenadas. Offsets recibe la media de cada
+// usuario, para el coseno ajustado.
+func (m *RatingMatrix) itemCoRatings(c *CoRatings, i, j int32) {
+    c.reset()
+    a, aValues := m.ItemColumn(i)
+    b, bValues := m.ItemColumn(j)
+    c.CountA, c.CountB = len(a), len(b)
+    c.MeanA, c.MeanB = m.itemMeans[i], m.itemMeans[j]
+    for x, y := 0, 0; x < len(a) && y < len(b); {
+        switch {
+        case a[x] < b[y]:
+            x++
+        case a[x] > b[y]:
+            y++
+        default:
+            c.A = append(c.A, float64(aValues[x]))
+            c.B = append(c.B, float64(bValues[y]))
+            c.Offsets = append(c.Offsets, m.userStats[a[x]].mean)
+            x++
+            y++
+        }
+    }
+}
+
+// Usuarios que comparten al menos un ítem con u, sin incluirlo
+func (m *RatingMatrix) coRaters(u int32) []int32 {
+    seen := make([]bool, m.users.Len())
+    seen[u] = true
+    var candidates []int32
+    items, _ := m.UserRow(u)
+    for _, i := range items {
+        raters, _ := m.ItemColumn(i)
+        for _, v := range raters {
+            if !seen[v] {
+                seen[v] = true
+                candidates = append(candidates, v)
+            }
+        }
+    }
+    return candidates
+}
+
+// Seleccionar en paralelo, entre los candidatos, los vecinos más similares
+// al usuario u según opts, de mayor a menor similitud. El orden no depende
+// del reparto entre workers, así que las sumas posteriores tampoco.
+func (m *RatingMatrix) selectNeighbors(u int32, candidates []int32, opts UserBasedOptions) []scoredItem {
+    similarityOf := opts.similarity()
+    workers := min(runtime.NumCPU(), len(candidates))
+    selections := make([]neighborSelection, workers)
+    buffers := make([]CoRatings, workers)
+    for w := range selections {
+        selections[w].limit = opts.Neighbors
+    }
+    parallelFor(len(candidates), func(w, x int) {
+        v := candidates[x]
+        m.userCoRatings(&buffers[w], u, v)
+        if similarity, ok := opts.neighborSimilarity(similarityOf, &buffers[w]); ok {
+            selections[w].offer(scoredItem{item: m.users.Name(v), id: int(v), score: similarity})
+        }
+    })
+
+    selection := &neighborSelection{limit: opts.Neighbors}
+    for w := range selections {
+        selection.merge(&selections[w])
+    }
+    neighbors := selection.candidates
+    sort.Slice(neighbors, func(a, b int) bool { return ranksBelow(neighbors[b], neighbors[a]) })
+    return neighbors
+}
+
+// Generar las topN recomendaciones basadas en usuarios para un usuario de
+// la matriz. Es la implementación de RecommendSequencial y
+// RecommendConcurrent: los candidatos salen de la vista CSC, sus
+// similitudes se calculan en paralelo cruzando filas ordenadas y las
+// calificaciones de los opts.Neighbors más similares se acumulan en
+// vectores densos, en orden de similitud, para que el resultado sea el
+// mismo en cada llamada.
+func RecommendCSR(m *RatingMatrix, user string, topN int, opts UserBasedOptions) []string {
+    u, exists := m.users.ID(user)
+    if !exists || topN <= 0 {
+        return []string{}
+    }
+    neighbors := m.selectNeighbors(u, m.coRaters(u), opts)
+
+    rated := make([]bool, m.items.Len())
+    userItems, _ := m.UserRow(u)
+    for _, i := range userItems {
+        rated[i] = true
+    }
+    scores := make([]float64, m.items.Len())
+    similaritySums := make([]float64, m.items.Len())
+    for _, neighbor := range neighbors {
+        v := int32(neighbor.id)
+        stats := m.userStats[v]
+        items, values := m.UserRow(v)
+        for y, i := range items {
+            if rated[i] {
+                continue
+            }
+            scores[i] += neighbor.score * opts.Normalization.normalize(float64(values[y]), stats)
+            similaritySums[i] += neighbor.score
+        }
+    }
+
+    h := make(scoreHeap, 0, topN+1)
+    for i, similaritySum := range similaritySums {
+        if similaritySum == 0 {
+            continue
+        }
+        score := opts.Normalization.denormalize(scores[i]/similaritySum, m.userStats[u])
+        h.offer(scoredItem{item: m.items.Name(int32(i)), id: i, score: score}, topN)
+    }
+    ranked := h.drain()
+    recommendations := make([]string, len(ranked))
+    for x, r := range ranked {
+        recommendations[x] = r.item
+    }
+    return recommendations
+}
+
+// Estimar la calificación de un usuario a un ítem de la matriz a partir de
+// los opts.Neighbors usuarios más similares a él que lo calificaron. Es la
+// implementación de PredictRatingSequencial y PredictRatingConcurrent.
+func PredictRatingCSR(m *RatingMatrix, user, item string, opts UserBasedOptions) Prediction {
+    u, userExists := m.users.ID(user)
+    if !userExists {
+        return Prediction{}
+    }
+    i, itemExists := m.items.ID(item)
+    if !itemExists {
+        return Prediction{Rating: m.userStats[u].mean}
+    }
+    raters, _ := m.ItemColumn(i)
+    candidates := make([]int32, 0, len(raters))
+    for _, v := range raters {
+        if v != u {
+            candidates = append(candidates, v)
+        }
+    }
+
+    var sum predictionSum
+    for _, neighbor := range m.selectNeighbors(u, candidates, opts) {
+        v := int32(neighbor.id)
+        rating, _ := m.rating(v, i)
+        sum.add(neighbor.score, opts.Normalization.normalize(rating, m.userStats[v]))
+    }
+    return sum.prediction(opts.Normalization, m.userStats[u])
+}
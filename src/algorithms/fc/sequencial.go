@@ -1,84 +1,83 @@
 package fc
 
-import (
-	"math"
-	"sort"
-)
-
-// Estructura para almacenar las calificaciones
+// Estructura para almacenar las calificaciones. Usuarios e ítems se
+// internan en dos diccionarios y las calificaciones de cada usuario se
+// guardan por identificador de ítem. Las lecturas de los recomendadores se
+// hacen sobre una instantánea como RatingMatrix, que se reconstruye tras
+// cada alta.
 type RatingsSequencial struct {
-	data map[string]map[string]float64
+	users  *Dictionary
+	items  *Dictionary
+	rows   []map[int32]float32
+	matrix *RatingMatrix
 }
 
 // Constructor para la estructura RatingsSequencial
 func NewRatingsSequencial() *RatingsSequencial {
-	return &RatingsSequencial{data: make(map[string]map[string]float64)}
+	return &RatingsSequencial{users: NewDictionary(), items: NewDictionary()}
 }
 
 // Añadir una calificación
 func (r *RatingsSequencial) AddRatingSequencial(user, item string, rating float64) {
-	if _, exists := r.data[user]; !exists {
-		r.data[user] = make(map[string]float64)
+	u := r.users.Intern(user)
+	if int(u) == len(r.rows) {
+		r.rows = append(r.rows, make(map[int32]float32))
 	}
-	r.data[user][item] = rating
+	r.rows[u][r.items.Intern(item)] = float32(rating)
+	r.matrix = nil
 }
 
-// Calcular la similitud del coseno entre dos usuarios
-func CosineSimilaritySequencial(ratings *RatingsSequencial, user1, user2 string) float64 {
-	commonItems := make(map[string]bool)
-	for item := range ratings.data[user1] {
-		if _, exists := ratings.data[user2][item]; exists {
-			commonItems[item] = true
-		}
+// Obtener una copia de las calificaciones de un usuario
+func (r *RatingsSequencial) UserRatingsSequencial(user string) map[string]float64 {
+	u, exists := r.users.ID(user)
+	if !exists {
+		return map[string]float64{}
 	}
+	return namedRatings(r.rows[u], r.items.Name)
+}
 
-	if len(commonItems) == 0 {
-		return 0.0
+// Recorrer las calificaciones de cada usuario, en orden de alta
+func (r *RatingsSequencial) EachUser(fn func(user string, ratings map[string]float64)) {
+	for u, ratings := range r.rows {
+		fn(r.users.Name(int32(u)), namedRatings(ratings, r.items.Name))
 	}
+}
 
-	var sum1, sum2, sumProduct float64
-	for item := range commonItems {
-		sum1 += ratings.data[user1][item] * ratings.data[user1][item]
-		sum2 += ratings.data[user2][item] * ratings.data[user2][item]
-		sumProduct += ratings.data[user1][item] * ratings.data[user2][item]
+// Instantánea de las calificaciones como RatingMatrix, construida en la
+// primera llamada tras un alta. Las altas posteriores no la modifican.
+func (r *RatingsSequencial) Matrix() *RatingMatrix {
+	if r.matrix == nil {
+		var entries []matrixEntry
+		for u, ratings := range r.rows {
+			for i, rating := range ratings {
+				entries = append(entries, matrixEntry{user: int32(u), item: i, rating: rating})
+			}
+		}
+		r.matrix = newSortedRatingMatrix(r.users.Names(), r.items.Names(), entries)
 	}
+	return r.matrix
+}
 
-	return sumProduct / (math.Sqrt(sum1) * math.Sqrt(sum2))
+// Calcular la similitud del coseno entre dos usuarios
+func CosineSimilaritySequencial(ratings *RatingsSequencial, user1, user2 string) float64 {
+	return SimilaritySequencial(ratings, Cosine{}, user1, user2)
 }
 
-// Generar recomendaciones para un usuario
-func RecommendSequencial(ratings *RatingsSequencial, user string, k int) []string {
-	scores := make(map[string]float64)
-	similaritySums := make(map[string]float64)
-	for otherUser := range ratings.data {
-		if otherUser == user {
-			continue
-		}
-		similarity := CosineSimilaritySequencial(ratings, user, otherUser)
-		if similarity <= 0 {
-			continue
-		}
+// Calcular la similitud entre dos usuarios con la medida indicada
+func SimilaritySequencial(ratings *RatingsSequencial, similarity Similarity, user1, user2 string) float64 {
+	return ratings.Matrix().userSimilarity(similarity, user1, user2)
+}
 
-		for item, rating := range ratings.data[otherUser] {
-			if _, exists := ratings.data[user][item]; exists {
-				continue
-			}
+// Generar las topN recomendaciones para un usuario, combinando las
+// calificaciones de sus opts.Neighbors vecinos más similares. Se calculan
+// con RecommendCSR sobre la instantánea del almacén.
+func RecommendSequencial(ratings *RatingsSequencial, user string, topN int, opts UserBasedOptions) []string {
+	return RecommendCSR(ratings.Matrix(), user, topN, opts)
+}
 
-			scores[item] += similarity * rating
-			similaritySums[item] += similarity
-		}
-	}
-	recommendations := make([]string, 0, k)
-	for item := range scores {
-		scores[item] /= similaritySums[item]
-		recommendations = append(recommendations, item)
-	}
-	// Ordenar las recomendaciones por puntuación (descendente)
-	sort.Slice(recommendations, func(i, j int) bool {
-		return scores[recommendations[i]] > scores[recommendations[j]]
-	})
-	if len(recommendations) > k {
-		return recommendations[:k]
-	}
-	return recommendations
-}
\ No newline at end of file
+// Estimar la calificación de un usuario a un ítem a partir de los
+// opts.Neighbors usuarios más similares a él que lo calificaron, con
+// PredictRatingCSR sobre la instantánea del almacén
+func PredictRatingSequencial(ratings *RatingsSequencial, user, item string, opts UserBasedOptions) Prediction {
+	return PredictRatingCSR(ratings.Matrix(), user, item, opts)
+}
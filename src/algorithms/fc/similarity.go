@@ -0,0 +1,146 @@
+package fc
+
+import "math"
+
+// Calificaciones en común de dos vectores: dos usuarios (sobre los ítems que
+// ambos calificaron) o dos ítems (sobre los usuarios que calificaron ambos).
+// A y B están emparejadas por posición. Offsets, cuando no es nil, guarda
+// para cada par la media del otro eje: la media del usuario al comparar
+// ítems. MeanA, MeanB, CountA y CountB describen todas las calificaciones de
+// cada vector, no solo las comunes.
+type CoRatings struct {
+    A, B           []float64
+    Offsets        []float64
+    MeanA, MeanB   float64
+    CountA, CountB int
+}
+
+// Número de calificaciones en común
+func (c *CoRatings) Len() int {
+    return len(c.A)
+}
+
+// Vaciar las calificaciones reutilizando la memoria
+func (c *CoRatings) reset() {
+    c.A, c.B, c.Offsets = c.A[:0], c.B[:0], c.Offsets[:0]
+}
+
+// Medida de similitud entre dos vectores de calificaciones, seleccionable en
+// los recomendadores basados en usuarios y en ítems
+type Similarity interface {
+    Similarity(c *CoRatings) float64
+}
+
+// Coseno sobre las calificaciones en bruto. Con un solo ítem en común la
+// similitud es siempre 1, por lo que conviene combinarlo con Shrunk.
+type Cosine struct{}
+
+func (Cosine) Similarity(c *CoRatings) float64 {
+    var sumA, sumB, sumProduct float64
+    for x := range c.A {
+        sumA += c.A[x] * c.A[x]
+        sumB += c.B[x] * c.B[x]
+        sumProduct += c.A[x] * c.B[x]
+    }
+    return safeRatio(sumProduct, math.Sqrt(sumA)*math.Sqrt(sumB))
+}
+
+// Coseno tras restar a cada vector la media de todas sus calificaciones, lo
+// que compensa a quien califica sistemáticamente alto o bajo
+type MeanCenteredCosine struct{}
+
+func (MeanCenteredCosine) Similarity(c *CoRatings) float64 {
+    return centeredCosine(c, func(x int) (float64, float64) { return c.MeanA, c.MeanB })
+}
+
+// Coseno ajustado para ítems: a cada calificación se le resta la media del
+// usuario que la dio. Sin Offsets, como al comparar usuarios, equivale a
+// MeanCenteredCosine.
+type AdjustedCosine struct{}
+
+func (AdjustedCosine) Similarity(c *CoRatings) float64 {
+    if c.Offsets == nil {
+        return MeanCenteredCosine{}.Similarity(c)
+    }
+    return centeredCosine(c, func(x int) (float64, float64) { return c.Offsets[x], c.Offsets[x] })
+}
+
+// Correlación de Pearson sobre las calificaciones en común, centradas en su
+// media común. Es 0 si alguno de los vectores es constante en ellas.
+type Pearson struct{}
+
+func (Pearson) Similarity(c *CoRatings) float64 {
+    if c.Len() == 0 {
+        return 0
+    }
+    var meanA, meanB float64
+    for x := range c.A {
+        meanA += c.A[x]
+        meanB += c.B[x]
+    }
+    meanA /= float64(c.Len())
+    meanB /= float64(c.Len())
+    return centeredCosine(c, func(x int) (float64, float64) { return meanA, meanB })
+}
+
+// Índice de Jaccard para datos implícitos: calificaciones en común entre
+// calificaciones de cualquiera de los dos, ignorando los valores
+type Jaccard struct{}
+
+func (Jaccard) Similarity(c *CoRatings) float64 {
+    return safeRatio(float64(c.Len()), float64(c.CountA+c.CountB-c.Len()))
+}
+
+// Contracción de otra medida según el número n de calificaciones en común:
+// Base · n / (n + Beta). Beta, por defecto 100, fija cuántas calificaciones
+// comunes hacen falta para confiar en la mitad de la similitud.
+type Shrunk struct {
+    Base Similarity
+    Beta float64
+}
+
+func (s Shrunk) Similarity(c *CoRatings) float64 {
+    beta := s.Beta
+    if beta == 0 {
+        beta = 100
+    }
+    n := float64(c.Len())
+    return s.Base.Similarity(c) * n / (n + beta)
+}
+
+// Ponderación por significancia: la similitud de Base se multiplica por
+// min(n, Threshold) / Threshold, con n las calificaciones en común y
+// Threshold por defecto 50
+type SignificanceWeighted struct {
+    Base      Similarity
+    Threshold int
+}
+
+func (s SignificanceWeighted) Similarity(c *CoRatings) float64 {
+    threshold := s.Threshold
+    if threshold == 0 {
+        threshold = 50
+    }
+    return s.Base.Similarity(c) * float64(min(c.Len(), threshold)) / float64(threshold)
+}
+
+// Coseno de las calificaciones en común tras restarles las medias que
+// devuelve means para cada par
+func centeredCosine(c *CoRatings, means func(x int) (float64, float64)) float64 {
+    var sumA, sumB, sumProduct float64
+    for x := range c.A {
+        meanA, meanB := means(x)
+        a, b := c.A[x]-meanA, c.B[x]-meanB
+        sumA += a * a
+        sumB += b * b
+        sumProduct += a * b
+    }
+    return safeRatio(sumProduct, math.Sqrt(sumA)*math.Sqrt(sumB))
+}
+
+func safeRatio(num, den float64) float64 {
+    if den == 0 {
+        return 0
+    }
+    return num / den
+}
@@ -0,0 +1,73 @@
+package fc
+
+import "container/heap"
+
+// Ítem candidato con su puntuación; id es su índice cuando lo hay
+type scoredItem struct {
+    item  string
+    id    int
+    score float64
+}
+
+// Montículo de mínimos por puntuación; en caso de empate queda arriba el
+// ítem de mayor nombre, para que el resultado sea determinista
+type scoreHeap []scoredItem
+
+// Indica si a queda por detrás de b en el ranking. Las puntuaciones se
+// comparan de forma exacta, para que sea un orden estricto válido para
+// sort y container/heap; quien las calcula debe sumarlas siempre en el
+// mismo orden para que el ranking no varíe entre llamadas.
+func ranksBelow(a, b scoredItem) bool {
+    if a.score != b.score {
+        return a.score < b.score
+    }
+    return a.item > b.item
+}
+
+func (h scoreHeap) Len() int           { return len(h) }
+func (h scoreHeap) Less(i, j int) bool { return ranksBelow(h[i], h[j]) }
+func (h scoreHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *scoreHeap) Push(x any)        { *h = append(*h, x.(scoredItem)) }
+func (h *scoreHeap) Pop() any {
+    old := *h
+    x := old[len(old)-1]
+    *h = old[:len(old)-1]
+    return x
+}
+
+// Ofrecer un candidato a un montículo que conserva los k mejores
+func (h *scoreHeap) offer(candidate scoredItem, k int) {
+    if len(*h) < k {
+        heap.Push(h, candidate)
+    } else if k > 0 && ranksBelow((*h)[0], candidate) {
+        (*h)[0] = candidate
+        heap.Fix(h, 0)
+    }
+}
+
+// Vaciar el montículo devolviendo sus elementos en orden descendente
+func (h *scoreHeap) drain() []scoredItem {
+    items := make([]scoredItem, h.Len())
+    for i := len(items) - 1; i >= 0; i-- {
+        items[i] = heap.Pop(h).(scoredItem)
+    }
+    return items
+}
+
+// Seleccionar los k ítems de mayor puntuación en orden descendente, en
+// O(n log k) con un montículo de tamaño k en lugar de ordenar todos
+func topK(scores map[string]float64, k int) []string {
+    if k <= 0 {
+        return []string{}
+    }
+    h := make(scoreHeap, 0, min(k, len(scores))+1)
+    for item, score := range scores {
+        h.offer(scoredItem{item: item, score: score}, k)
+    }
+    ranked := h.drain()
+    items := make([]string, len(ranked))
+    for i, r := range ranked {
+        items[i] = r.item
+    }
+    return items
+}
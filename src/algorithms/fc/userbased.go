@@ -0,0 +1,110 @@
+package fc
+
+import "math"
+
+// Normalización de las calificaciones de los vecinos antes de combinarlas.
+// La estimación se devuelve en la escala del usuario que recibe la
+// recomendación.
+type Normalization int
+
+const (
+    // Media ponderada de las calificaciones en bruto
+    NoNormalization Normalization = iota
+    // Se combinan las desviaciones de cada vecino respecto de su media y el
+    // resultado se suma a la media del usuario
+    MeanCentering
+    // Como MeanCentering, dividiendo además cada desviación por la
+    // desviación típica del vecino y multiplicando el resultado por la del
+    // usuario
+    ZScore
+)
+
+// Opciones de los recomendadores basados en usuarios. Los valores a cero
+// reproducen el comportamiento original: coseno, todos los usuarios con
+// similitud positiva como vecinos y calificaciones sin normalizar.
+type UserBasedOptions struct {
+    Similarity    Similarity    // medida de similitud, por defecto Cosine
+    Neighbors     int           // vecinos más similares que se combinan; 0 los usa todos
+    MinCoRated    int           // ítems en común necesarios para ser vecino, por defecto 1
+    Normalization Normalization // normalización de las calificaciones
+}
+
+func (o UserBasedOptions) similarity() Similarity {
+    if o.Similarity == nil {
+        return Cosine{}
+    }
+    return o.Similarity
+}
+
+// Similitud de un posible vecino, o false si no tiene suficientes ítems en
+// común o su similitud no es positiva
+func (o UserBasedOptions) neighborSimilarity(similarity Similarity, c *CoRatings) (float64, bool) {
+    if c.Len() == 0 || c.Len() < o.MinCoRated {
+        return 0, false
+    }
+    s := similarity.Similarity(c)
+    return s, s > 0
+}
+
+// Media y desviación típica de las calificaciones de un usuario
+type ratingStats struct {
+    mean, std float64
+}
+
+func statsOf(ratings map[string]float64) ratingStats {
+    if len(ratings) == 0 {
+        return ratingStats{}
+    }
+    var sum, sumSquares float64
+    for _, rating := range ratings {
+        sum += rating
+        sumSquares += rating * rating
+    }
+    n := float64(len(ratings))
+    mean := sum / n
+    return ratingStats{mean: mean, std: math.Sqrt(max(sumSquares/n-mean*mean, 0))}
+}
+
+// Llevar una calificación de un vecino a la escala común
+func (n Normalization) normalize(rating float64, stats ratingStats) float64 {
+    switch n {
+    case MeanCentering:
+        return rating - stats.mean
+    case ZScore:
+        return safeRatio(rating-stats.mean, stats.std)
+    }
+    return rating
+}
+
+// Devolver una estimación de la escala común a la del usuario
+func (n Normalization) denormalize(score float64, stats ratingStats) float64 {
+    switch n {
+    case MeanCentering:
+        return stats.mean + score
+    case ZScore:
+        return stats.mean + score*stats.std
+    }
+    return score
+}
+
+// Selección de los vecinos más similares. Con un límite positivo conserva
+// los limit mejores en un montículo; sin él, todos los ofrecidos.
+type neighborSelection struct {
+    limit      int
+    candidates scoreHeap
+}
+
+func (s *neighborSelection) offer(candidate scoredItem) {
+    if s.limit <= 0 {
+        s.candidates = append(s.candidates, candidate)
+        return
+    }
+    s.candidates.offer(candidate, s.limit)
+}
+
+// Combinar los vecinos seleccionados por otra selección
+func (s *neighborSelection) merge(other *neighborSelection) {
+    for _, candidate := range other.candidates {
+        s.offer(candidate)
+    }
+}
@@ -1,6 +1,10 @@
 package fc
 
-import (    "fmt"
+import (
+    "errors"
+    "fmt"
+    "math"
+    "math/rand"
 )
 
 // DenseMatrix representa una matriz densa.
@@ -22,6 +26,21 @@ func MakeDenseMatrix(data []float64, rows, cols int) *DenseMatrix {
     }
 }
 
+// NewDenseMatrix crea una matriz de ceros de rows × cols.
+func NewDenseMatrix(rows, cols int) *DenseMatrix {
+    return MakeDenseMatrix(make([]float64, rows*cols), rows, cols)
+}
+
+// RandomDenseMatrix crea una matriz con valores normales de media cero y
+// desviación std, tomados de rng.
+func RandomDenseMatrix(rows, cols int, std float64, rng *rand.Rand) *DenseMatrix {
+    m := NewDenseMatrix(rows, cols)
+    for i := range m.data {
+        m.data[i] = rng.NormFloat64() * std
+    }
+    return m
+}
+
 // Array devuelve los datos de la matriz como un slice unidimensional.
 func (m *DenseMatrix) Array() []float64 {
     return m.data
@@ -75,6 +94,151 @@ func (m *DenseMatrix) GetColVector(j int) *DenseMatrix {
     return MakeDenseMatrix(col, m.rows, 1)
 }
 
+// Row devuelve la fila i como un slice que comparte los datos de la matriz,
+// a diferencia de GetRowVector, que la copia.
+func (m *DenseMatrix) Row(i int) []float64 {
+    if i < 0 || i >= m.rows {
+        panic("index out of range")
+    }
+    return m.data[i*m.cols : (i+1)*m.cols]
+}
+
+// Zero pone a cero todos los valores.
+func (m *DenseMatrix) Zero() {
+    clear(m.data)
+}
+
+// Transpose devuelve la traspuesta de la matriz.
+func (m *DenseMatrix) Transpose() *DenseMatrix {
+    t := NewDenseMatrix(m.cols, m.rows)
+    for i := 0; i < m.rows; i++ {
+        for j := 0; j < m.cols; j++ {
+            t.data[j*m.rows+i] = m.data[i*m.cols+j]
+        }
+    }
+    return t
+}
+
+// Mul devuelve el producto matricial m · b.
+func (m *DenseMatrix) Mul(b *DenseMatrix) *DenseMatrix {
+    if m.cols != b.rows {
+        panic("dimension mismatch")
+    }
+    result := NewDenseMatrix(m.rows, b.cols)
+    for i := 0; i < m.rows; i++ {
+        row := result.Row(i)
+        for k, a := range m.Row(i) {
+            if a == 0 {
+                continue
+            }
+            for j, v := range b.Row(k) {
+                row[j] += a * v
+            }
+        }
+    }
+    return result
+}
+
+// MulVec devuelve el producto m · v.
+func (m *DenseMatrix) MulVec(v []float64) []float64 {
+    if m.cols != len(v) {
+        panic("dimension mismatch")
+    }
+    result := make([]float64, m.rows)
+    for i := range result {
+        result[i] = Dot(m.Row(i), v)
+    }
+    return result
+}
+
+// AddOuter suma alpha · a bᵀ a la matriz.
+func (m *DenseMatrix) AddOuter(alpha float64, a, b []float64) {
+    if len(a) != m.rows || len(b) != m.cols {
+        panic("dimension mismatch")
+    }
+    for i, x := range a {
+        row := m.Row(i)
+        for j, y := range b {
+            row[j] += alpha * x * y
+        }
+    }
+}
+
+// AddDiagonal suma value a cada elemento de la diagonal.
+func (m *DenseMatrix) AddDiagonal(value float64) {
+    for i := 0; i < min(m.rows, m.cols); i++ {
+        m.data[i*m.cols+i] += value
+    }
+}
+
+// ErrNotPositiveDefinite indica que Cholesky recibió una matriz que no es
+// simétrica definida positiva.
+var ErrNotPositiveDefinite = errors.New("matrix is not positive definite")
+
+// Cholesky factoriza en el lugar una matriz simétrica definida positiva como
+// L · Lᵀ, dejando L en el triángulo inferior. Solo se lee el triángulo
+// inferior de la matriz original.
+func (m *DenseMatrix) Cholesky() error {
+    if m.rows != m.cols {
+        panic("matrix must be square")
+    }
+    n := m.rows
+    for j := 0; j < n; j++ {
+        sum := m.data[j*n+j]
+        for k := 0; k < j; k++ {
+            sum -= m.data[j*n+k] * m.data[j*n+k]
+        }
+        if sum <= 0 {
+            return ErrNotPositiveDefinite
+        }
+        diag := math.Sqrt(sum)
+        m.data[j*n+j] = diag
+        for i := j + 1; i < n; i++ {
+            sum := m.data[i*n+j]
+            for k := 0; k < j; k++ {
+                sum -= m.data[i*n+k] * m.data[j*n+k]
+            }
+            m.data[i*n+j] = sum / diag
+        }
+    }
+    return nil
+}
+
+// CholeskySolve resuelve en el lugar L · Lᵀ · x = b, sobrescribiendo b con x,
+// para una matriz ya factorizada con Cholesky.
+func (m *DenseMatrix) CholeskySolve(b []float64) {
+    n := m.rows
+    if len(b) != n {
+        panic("dimension mismatch")
+    }
+    for i := 0; i < n; i++ {
+        sum := b[i]
+        for k := 0; k < i; k++ {
+            sum -= m.data[i*n+k] * b[k]
+        }
+        b[i] = sum / m.data[i*n+i]
+    }
+    for i := n - 1; i >= 0; i-- {
+        sum := b[i]
+        for k := i + 1; k < n; k++ {
+            sum -= m.data[k*n+i] * b[k]
+        }
+        b[i] = sum / m.data[i*n+i]
+    }
+}
+
+// Dot devuelve el producto escalar de dos vectores.
+func Dot(a, b []float64) float64 {
+    if len(a) != len(b) {
+        panic("dimension mismatch")
+    }
+    var sum float64
+    for i := range a {
+        sum += a[i] * b[i]
+    }
+    return sum
+}
+
 // String devuelve una representación en cadena de la matriz.
 func (m *DenseMatrix) String() string {
     result := ""
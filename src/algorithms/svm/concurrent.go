@@ -2,6 +2,8 @@ package svm
 
 import (
     "fmt"
+    "math"
+    "runtime"
     "sync"
 )
 
@@ -11,6 +13,16 @@ type SVMC struct {
     Bias         float64
     LearningRate float64
     Iterations   int
+    Introspect   func(step StepConcurrent)
+}
+
+// StepConcurrent captures status updates that happen within a single epoch, for
+// use in introspecting models.
+type StepConcurrent struct {
+    Epoch          int
+    HingeLoss      float64
+    Objective      float64
+    SupportVectors int
 }
 
 // SVMConcurrent creates a new SVMC model with given parameters
@@ -21,64 +33,139 @@ func SVMConcurrent(learningRate float64, iterations int) *SVMC {
     }
 }
 
-// TrainConcurrent trains the SVM model using goroutines
+// TrainConcurrent trains the SVMC model on the same regularized hinge-loss
+// objective as TrainSequencial, one batch step per epoch. The points with
+// y·(w·x+b) <= 1 are found in parallel against the weights of the epoch and
+// their hinge-loss subgradients are summed per goroutine and then applied
+// at once, while the weights decay by the L2 term as they would over one
+// sequential pass.
 func (s *SVMC) TrainConcurrent(X [][]float64, Y []float64) {
 	numSamples := len(X)
 	numFeatures := len(X[0])
 	s.Weights = make([]float64, numFeatures)
+	decay := math.Pow(1-2*regularization*s.LearningRate, float64(numSamples))
 
-	var wg sync.WaitGroup
-	mutex := &sync.Mutex{}
-
-	weightUpdates := make([][]float64, numSamples)
-	biasUpdates := make([]float64, numSamples)
-
+	var mutex sync.Mutex
+	weightUpdate := make([]float64, numFeatures)
 	for i := 0; i < s.Iterations; i++ {
-		for j := range weightUpdates {
-			weightUpdates[j] = make([]float64, numFeatures)
-		}
-
-		for j := 0; j < numSamples; j++ {
-			wg.Add(1)
-			go func(index int) {
-				defer wg.Done()
-				dot := s.dotProduct(s.Weights, X[index])
-				if Y[index]*dot <= 0 {
-					// Calculate updates
-					weightChange := make([]float64, numFeatures)
+		clear(weightUpdate)
+		biasUpdate := 0.0
+		parallelChunks(numSamples, func(start, end int) {
+			localWeights := make([]float64, numFeatures)
+			localBias := 0.0
+			for j := start; j < end; j++ {
+				if Y[j]*(s.dotProduct(s.Weights, X[j])+s.Bias) <= 1 {
 					for k := 0; k < numFeatures; k++ {
-						weightChange[k] = s.LearningRate * Y[index] * X[index][k]
+						localWeights[k] += Y[j] * X[j][k]
 					}
-					biasChange := s.LearningRate * Y[index]
-					mutex.Lock()
-					weightUpdates[index] = weightChange
-					biasUpdates[index] = biasChange
-					mutex.Unlock()
+					localBias += Y[j]
 				}
-			}(j)
-		}
-		wg.Wait()
-		mutex.Lock()
-		for j := 0; j < numSamples; j++ {
-			if weightUpdates[j] != nil {
-				for k := 0; k < numFeatures; k++ {
-					s.Weights[k] += weightUpdates[j][k]
-				}
-				s.Bias += biasUpdates[j]
 			}
+			mutex.Lock()
+			for k := range weightUpdate {
+				weightUpdate[k] += localWeights[k]
+			}
+			biasUpdate += localBias
+			mutex.Unlock()
+		})
+		for k := range s.Weights {
+			s.Weights[k] = decay*s.Weights[k] + s.LearningRate*weightUpdate[k]
+		}
+		s.Bias += s.LearningRate * biasUpdate
+
+		if s.Introspect != nil {
+			margins := s.DecisionFunction(X)
+			hinge, supports := hingeLossConcurrent(margins, Y)
+			s.Introspect(StepConcurrent{
+				Epoch:          i,
+				HingeLoss:      hinge,
+				Objective:      hinge + regularization*s.dotProduct(s.Weights, s.Weights),
+				SupportVectors: supports,
+			})
 		}
-		mutex.Unlock()
 	}
 }
 
+// DecisionFunction returns the raw margin w·x+b for every row of X, splitting
+// the rows across goroutines
+func (s *SVMC) DecisionFunction(X [][]float64) []float64 {
+    margins := make([]float64, len(X))
+    parallelChunks(len(X), func(start, end int) {
+        for i := start; i < end; i++ {
+            margins[i] = s.dotProduct(s.Weights, X[i]) + s.Bias
+        }
+    })
+    return margins
+}
+
+// SupportVectorsConcurrent returns the indices of the training points lying on
+// or inside the margin, that is, those with y·(w·x+b) <= 1
+func (s *SVMC) SupportVectorsConcurrent(X [][]float64, Y []float64) []int {
+    margins := s.DecisionFunction(X)
+    var indices []int
+    for i, margin := range margins {
+        if Y[i]*margin <= 1 {
+            indices = append(indices, i)
+        }
+    }
+    return indices
+}
+
+// hingeLossConcurrent returns the mean hinge loss for the given margins along
+// with the number of points with a non-zero loss contribution, reducing
+// partial sums computed by each goroutine
+func hingeLossConcurrent(margins, Y []float64) (float64, int) {
+    if len(margins) == 0 {
+        return 0, 0
+    }
+    var mu sync.Mutex
+    loss := 0.0
+    supports := 0
+    parallelChunks(len(margins), func(start, end int) {
+        localLoss := 0.0
+        localSupports := 0
+        for i := start; i < end; i++ {
+            if Y[i]*margins[i] <= 1 {
+                localLoss += 1 - Y[i]*margins[i]
+                localSupports++
+            }
+        }
+        mu.Lock()
+        loss += localLoss
+        supports += localSupports
+        mu.Unlock()
+    })
+    return loss / float64(len(margins)), supports
+}
+
+// parallelChunks splits the range [0, n) into one contiguous chunk per CPU and
+// runs fn on each chunk in its own goroutine
+func parallelChunks(n int, fn func(start, end int)) {
+    numGoroutines := runtime.NumCPU()
+    chunkSize := (n + numGoroutines - 1) / numGoroutines
+    var wg sync.WaitGroup
+    for start := 0; start < n; start += chunkSize {
+        end := start + chunkSize
+        if end > n {
+            end = n
+        }
+        wg.Add(1)
+        go func(start, end int) {
+            defer wg.Done()
+            fn(start, end)
+        }(start, end)
+    }
+    wg.Wait()
+}
+
 // PredictConcurrent predicts the class for given input data
 func (s *SVMC) PredictConcurrent(X [][]float64) []float64 {
     numSamples := len(X)
     predictions := make([]float64, numSamples)
 
+    margins := s.DecisionFunction(X)
     for i := 0; i < numSamples; i++ {
-        dot := s.dotProduct(s.Weights, X[i]) + s.Bias
-        if dot >= 0 {
+        if margins[i] >= 0 {
             predictions[i] = 1
         } else {
             predictions[i] = -1
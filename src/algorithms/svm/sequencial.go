@@ -4,12 +4,26 @@ import (
 	"fmt"
 )
 
+// regularization is the L2 penalty applied to the weights by the hinge loss
+// objective.
+const regularization = 0.01
+
 // SVMS represents a simple linear SVMS model
 type SVMS struct {
 	Weights      []float64
 	Bias         float64
 	LearningRate float64
 	Iterations   int
+	Introspect   func(step StepSequencial)
+}
+
+// StepSequencial captures status updates that happen within a single epoch, for
+// use in introspecting models.
+type StepSequencial struct {
+	Epoch          int
+	HingeLoss      float64
+	Objective      float64
+	SupportVectors int
 }
 
 // SVMSequencial creates a new SVMS model with given parameters
@@ -32,17 +46,67 @@ func (s *SVMS) TrainSequencial(X [][]float64, Y []float64) {
 			if Y[j]*dot <= 1 {
 				// Update weights and bias using the hinge loss gradient
 				for k := 0; k < numFeatures; k++ {
-					s.Weights[k] += s.LearningRate * (Y[j]*X[j][k] - 2*0.01*s.Weights[k])
+					s.Weights[k] += s.LearningRate * (Y[j]*X[j][k] - 2*regularization*s.Weights[k])
 				}
 				s.Bias += s.LearningRate * Y[j]
 			} else {
 				// Update weights with regularization term
 				for k := 0; k < numFeatures; k++ {
-					s.Weights[k] -= s.LearningRate * 2 * 0.01 * s.Weights[k]
+					s.Weights[k] -= s.LearningRate * 2 * regularization * s.Weights[k]
 				}
 			}
 		}
+
+		if s.Introspect != nil {
+			margins := s.DecisionFunction(X)
+			hinge, supports := hingeLossSequencial(margins, Y)
+			s.Introspect(StepSequencial{
+				Epoch:          i,
+				HingeLoss:      hinge,
+				Objective:      hinge + regularization*s.dotProductSequencial(s.Weights, s.Weights),
+				SupportVectors: supports,
+			})
+		}
+	}
+}
+
+// DecisionFunction returns the raw margin w·x+b for every row of X
+func (s *SVMS) DecisionFunction(X [][]float64) []float64 {
+	margins := make([]float64, len(X))
+	for i := range X {
+		margins[i] = s.dotProductSequencial(s.Weights, X[i]) + s.Bias
+	}
+	return margins
+}
+
+// SupportVectorsSequencial returns the indices of the training points lying on
+// or inside the margin, that is, those with y·(w·x+b) <= 1
+func (s *SVMS) SupportVectorsSequencial(X [][]float64, Y []float64) []int {
+	margins := s.DecisionFunction(X)
+	var indices []int
+	for i, margin := range margins {
+		if Y[i]*margin <= 1 {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// hingeLossSequencial returns the mean hinge loss for the given margins along
+// with the number of points with a non-zero loss contribution
+func hingeLossSequencial(margins, Y []float64) (float64, int) {
+	if len(margins) == 0 {
+		return 0, 0
+	}
+	loss := 0.0
+	supports := 0
+	for i, margin := range margins {
+		if Y[i]*margin <= 1 {
+			loss += 1 - Y[i]*margin
+			supports++
+		}
 	}
+	return loss / float64(len(margins)), supports
 }
 
 // PredictSequencial predicts the class for given input data
@@ -50,9 +114,9 @@ func (s *SVMS) PredictSequencial(X [][]float64) []float64 {
 	numSamples := len(X)
 	predictions := make([]float64, numSamples)
 
+	margins := s.DecisionFunction(X)
 	for i := 0; i < numSamples; i++ {
-		dot := s.dotProductSequencial(s.Weights, X[i]) + s.Bias
-		if dot >= 0 {
+		if margins[i] >= 0 {
 			predictions[i] = 1
 		} else {
 			predictions[i] = -1
@@ -7,8 +7,8 @@ import (
 	"PC2/algorithms/dnn"
 	"PC2/algorithms/fc"
 	"PC2/utils"
+	"PC2/preprocessing"
 	"math/rand"
-	"strconv"
 )
 
 func main() {
@@ -46,6 +46,23 @@ func main() {
     //--------------------------------------------------------------SVM--------------------------------------------------------------
     //Separar datos de entrenamiento y prueba para SVM y DNN
 	trainX, trainY, testX, testY := utils.TrainTestSplit(xData, yData, 0.2)
+
+	//Estandarizar las características con las estadísticas del conjunto de entrenamiento
+	scaler := &preprocessing.StandardScaler{}
+	if err := scaler.Fit(trainX); err != nil {
+		fmt.Println("Error al ajustar el escalador:", err)
+		return
+	}
+	trainX, err := scaler.Transform(trainX)
+	if err != nil {
+		fmt.Println("Error al escalar el conjunto de entrenamiento:", err)
+		return
+	}
+	testX, err = scaler.Transform(testX)
+	if err != nil {
+		fmt.Println("Error al escalar el conjunto de prueba:", err)
+		return
+	}
 	
 	//Parámetros de entrenamiento
 	epochs := 10
@@ -67,112 +84,253 @@ func main() {
     })
 
     //--------------------------------------------------------------RNN--------------------------------------------------------------
-    trainXFrame := make(dnn.Frame, len(trainX))
-    for i, row := range trainX {
-        vector := make(dnn.Vector, len(row))
-        for j, val := range row {
-            vector[j] = float32(val)
-        }
-        trainXFrame[i] = vector
-    }
-    trainYFrame := make(dnn.Frame, len(trainY))
-    for i, val := range trainY {
-        vector := make(dnn.Vector, 1)
-        vector[0] = float32(val)
-        trainYFrame[i] = vector
-    }
-    testXFrame := make(dnn.Frame, len(testX))
-    for i, row := range testX {
-        vector := make(dnn.Vector, len(row))
-        for j, val := range row {
-            vector[j] = float32(val)
-        }
-        testXFrame[i] = vector
-    }
-    testYFrame := make(dnn.Frame, len(testY))
-    for i, val := range testY {
-        vector := make(dnn.Vector, 1)
-        vector[0] = float32(val)
-        testYFrame[i] = vector
-    }
+    trainXFrame, trainYFrame := dnn.NewFrame(trainX), dnn.LabelFrame(trainY)
+    testXFrame := dnn.NewFrame(testX)
 
 	//Parámetros de entrenamiento
 	inputSize := len(trainX[0])
 	outputSize := 1
 
-	utils.MeasureExecutionTime("DNN Secuencial", func() {
-		nn := &dnn.MLPSequencial{
-			Layers: []*dnn.LayerSequencial{
-				{Name: "Input Layer", Width: inputSize},
-				{Name: "Hidden Layer", Width: 10, ActivationFunction: dnn.Sigmoid, ActivationFunctionDeriv: dnn.SigmoidDerivative},
-				{Name: "Output Layer", Width: outputSize, ActivationFunction: dnn.Sigmoid, ActivationFunctionDeriv: dnn.SigmoidDerivative},
-			},
-			LearningRate: 0.1,
-			Introspect: func(step dnn.StepSequencial) {
-				fmt.Printf("Epoch: %d, Loss: %f\n", step.Epoch, step.LossSequencial)
-			},
+	// Misma red y mismos datos, solo cambia la estrategia de ejecución
+	pool := dnn.NewPool(0)
+	defer pool.Close()
+	strategies := []struct {
+		name     string
+		strategy dnn.Strategy
+		history  string
+	}{
+		{"DNN Secuencial", dnn.Sequential{}, "historial_dnn_secuencial.csv"},
+		{"DNN Concurrente", dnn.Sharded{}, "historial_dnn_concurrente.csv"},
+		{"DNN Pool", pool, "historial_dnn_pool.csv"},
+	}
+
+	for _, s := range strategies {
+		utils.MeasureExecutionTime(s.name, func() {
+			nn := &dnn.Network{
+				InputWidth: inputSize,
+				Layers: []dnn.Layer{
+					&dnn.Dense{Name: "Hidden Layer", Width: 10, Activation: dnn.SigmoidActivation{}},
+					&dnn.Dense{Name: "Output Layer", Width: outputSize, Activation: dnn.SigmoidActivation{}},
+				},
+				LearningRate: 0.01,
+				BatchSize: 32,
+				Loss: dnn.BinaryCrossEntropy{},
+				Optimizer: dnn.Adam{},
+				Strategy: s.strategy,
+				Seed: 42,
+				Introspect: func(step dnn.Step) {
+					fmt.Printf("Epoch: %d, Loss: %f\n", step.Epoch, step.Loss)
+				},
+			}
+			history, err := nn.Train(epochs, trainXFrame, trainYFrame)
+			if err != nil {
+				fmt.Println("Error durante el entrenamiento:", err)
+				return
+			}
+			fmt.Printf("Entrenamiento completado con pérdida final: %f\n", history.Loss())
+			if err := history.SaveCSV(s.history); err != nil {
+				fmt.Println("Error al guardar el historial:", err)
+			}
+			predicted := nn.PredictClass(testXFrame)
+			actual := make([]int, len(testY))
+			for i, label := range testY {
+				actual[i] = int(label)
+			}
+			confusion, err := dnn.NewConfusionMatrix(predicted, actual, 2)
+			if err != nil {
+				fmt.Println("Error al evaluar:", err)
+				return
+			}
+			fmt.Printf("Precisión: %.2f%%\n", confusion.Accuracy() * 100)
+			fmt.Print(confusion)
+		})
+	}
+
+	// Autoencoder entrenado solo con fondo: el error de reconstrucción sirve
+	// como puntuación de anomalía para la señal
+	utils.MeasureExecutionTime("DNN Autoencoder", func() {
+		var background dnn.Frame
+		for i, label := range trainY {
+			if label == 0 {
+				background = append(background, trainXFrame[i])
+			}
 		}
-		loss, err := nn.TrainSequencial(epochs, trainXFrame, trainYFrame)
+		ae := dnn.NewAutoencoder(inputSize,
+			[]dnn.Layer{&dnn.Dense{Name: "Encoder", Width: inputSize / 2, Activation: dnn.Tanh{}}},
+			[]dnn.Layer{&dnn.Dense{Name: "Decoder", Width: inputSize, Activation: dnn.Linear{}}},
+		)
+		ae.LearningRate = 0.01
+		ae.BatchSize = 32
+		ae.Optimizer = dnn.Adam{}
+		ae.Strategy = pool
+		history, err := ae.Train(epochs, background)
 		if err != nil {
 			fmt.Println("Error durante el entrenamiento:", err)
 			return
 		}
-		fmt.Printf("Entrenamiento completado con pérdida final: %f\n", loss)
-		predictions := nn.PredictSequencial(testXFrame)
-		accuracy := dnn.CalculateAccuracy(predictions, testY)
-		fmt.Printf("Precisión: %.2f%%\n", accuracy * 100)
-	})
-	
-	utils.MeasureExecutionTime("DNN Concurrente", func() {
-		nn := &dnn.MLPConcurrent{
-			Layers: []*dnn.LayerConcurrent{
-				{Name: "Input Layer", Width: inputSize},
-				{Name: "Hidden Layer", Width: 10, ActivationFunction: dnn.Sigmoid, ActivationFunctionDeriv: dnn.SigmoidDerivative},
-				{Name: "Output Layer", Width: outputSize, ActivationFunction: dnn.Sigmoid, ActivationFunctionDeriv: dnn.SigmoidDerivative},
-			},
-			LearningRate: 0.1,
-			Introspect: func(step dnn.StepConcurrent) {
-				fmt.Printf("Epoch: %d, Loss: %f\n", step.Epoch, step.LossConcurrent)
-			},
-		}
-		loss, err := nn.TrainConcurrent(epochs, trainXFrame, trainYFrame)
-		if err != nil {
-			fmt.Println("Error durante el entrenamiento:", err)
-			return
+		fmt.Printf("Autoencoder entrenado con pérdida final: %f\n", history.Loss())
+
+		var sums, counts [2]float64
+		for i, score := range ae.ReconstructionError(testXFrame) {
+			sums[int(testY[i])] += score
+			counts[int(testY[i])]++
 		}
-		fmt.Printf("Entrenamiento completado con pérdida final: %f\n", loss)
-		predictions := nn.PredictConcurrent(testXFrame)
-		accuracy := dnn.CalculateAccuracy(predictions, testY)
-		fmt.Printf("Precisión: %.2f%%\n", accuracy * 100)
+		fmt.Printf("Error de reconstrucción medio: fondo %.4f, señal %.4f\n", sums[0]/counts[0], sums[1]/counts[1])
 	})
 
     //--------------------------------------------------------------Filtrado colaborativo
     ratings1 := fc.NewRatingsSequencial()
     ratings2 := fc.NewRatingsConcurrent()
 
-	for _, record := range df_ratings[1:] {
-        user := fmt.Sprintf("User%s", record[0])
-        item := fmt.Sprintf("Item%s", record[1])
-        rating, err := strconv.ParseFloat(record[2], 64)
-        if err != nil {
-            fmt.Println("Error al convertir la calificación:", err)
-            return
-        }
-        ratings1.AddRatingSequencial(user, item, rating)
-        ratings2.AddRatingConcurrent(user, item, rating)
-    }
+    parseRating := fc.PrefixedRecordParser("User", "Item")
+
+	utils.MeasureExecutionTime("Carga FCSequencial", func() {
+		for _, record := range df_ratings[1:] {
+			user, item, rating, err := parseRating(record)
+			if err != nil {
+				fmt.Println("Error al convertir la calificación:", err)
+				return
+			}
+			ratings1.AddRatingSequencial(user, item, rating)
+		}
+	})
+	utils.MeasureExecutionTime("Carga FCConcurrent", func() {
+		if err := ratings2.LoadRatingsConcurrent(df_ratings[1:], parseRating, 0); err != nil {
+			fmt.Println("Error al convertir la calificación:", err)
+		}
+	})
+	// Matriz compacta con identificadores enteros, para los modelos que se
+	// entrenan sobre una instantánea de las calificaciones
+	var matrix *fc.RatingMatrix
+	utils.MeasureExecutionTime("Carga FCCSR", func() {
+		loaded, err := fc.LoadRatingMatrix(df_ratings[1:], parseRating)
+		if err != nil {
+			fmt.Println("Error al convertir la calificación:", err)
+			return
+		}
+		matrix = loaded
+	})
+	if matrix == nil {
+		return
+	}
 
-	// Parametros de recomendación
-    numUsers := 103170 
-    user := fmt.Sprintf("User%d", rand.Intn(numUsers))
+	// Parametros de recomendación: un usuario elegido al azar entre los que
+	// tienen calificaciones
+    knownUsers := matrix.Users().Names()
+    if len(knownUsers) == 0 {
+        fmt.Println("No hay calificaciones para recomendar")
+        return
+    }
+    user := knownUsers[rand.Intn(len(knownUsers))]
     k := 10
+    // Pearson contraído según las calificaciones en común, para que un par
+    // de usuarios con un solo ítem en común no cuente como idéntico, sobre
+    // los 50 vecinos más similares y con calificaciones centradas en la media
+    userBased := fc.UserBasedOptions{
+        Similarity:    fc.Shrunk{Base: fc.Pearson{}, Beta: 10},
+        Neighbors:     50,
+        MinCoRated:    3,
+        Normalization: fc.MeanCentering,
+    }
 
 	utils.MeasureExecutionTime("FCSequencial", func() {
-        recommendations := fc.RecommendSequencial(ratings1, user, k)
+        recommendations := fc.RecommendSequencial(ratings1, user, k, userBased)
         fmt.Printf("Recomendaciones secuenciales para %s: %v\n", user, recommendations)
     })
     utils.MeasureExecutionTime("FCConcurrent", func() {
-		recommendations := fc.RecommendConcurrent(ratings2, user, k)
+		recommendations := fc.RecommendConcurrent(ratings2, user, k, userBased)
 		fmt.Printf("Recomendaciones concurrentes para %s: %v\n", user, recommendations)
 	})
+	utils.MeasureExecutionTime("FCCSR", func() {
+		recommendations := fc.RecommendCSR(matrix, user, k, userBased)
+		fmt.Printf("Recomendaciones sobre la matriz compacta para %s: %v\n", user, recommendations)
+	})
+
+	// Filtrado colaborativo basado en ítems con vecinos precalculados
+	var neighbors *fc.ItemNeighbors
+	utils.MeasureExecutionTime("FCItemNeighbors", func() {
+		neighbors = fc.BuildItemNeighborsCSR(matrix, 20, fc.Shrunk{Base: fc.AdjustedCosine{}, Beta: 10}, 0)
+		if err := neighbors.Save("item_neighbors.gob"); err != nil {
+			fmt.Println("Error al guardar la tabla de vecinos:", err)
+		}
+	})
+	utils.MeasureExecutionTime("FCItemBased", func() {
+		recommendations := fc.RecommendItemBasedConcurrent(neighbors, ratings2, user, k)
+		fmt.Printf("Recomendaciones basadas en ítems para %s: %v\n", user, recommendations)
+	})
+
+	// Factorización matricial: ALS concurrente y SVD con sesgos por SGD
+	utils.MeasureExecutionTime("FCALSConcurrent", func() {
+		mf := &fc.MatrixFactorization{Factors: 20, Iterations: 10}
+		mf.TrainALSConcurrent(matrix)
+		recommendations := mf.Recommend(user, matrix.UserRatings(user), k)
+		fmt.Printf("Recomendaciones ALS para %s: %v\n", user, recommendations)
+	})
+	utils.MeasureExecutionTime("FCSGDSequencial", func() {
+		mf := &fc.MatrixFactorization{Factors: 20, Iterations: 20}
+		mf.TrainSGDSequencial(ratings1)
+		recommendations := mf.Recommend(user, ratings1.UserRatingsSequencial(user), k)
+		fmt.Printf("Recomendaciones SGD para %s: %v\n", user, recommendations)
+	})
+
+	// Retroalimentación implícita: cada calificación cuenta como una
+	// interacción, con ALS ponderado por confianza y con BPR
+	utils.MeasureExecutionTime("FCWALSConcurrent", func() {
+		implicit := &fc.ImplicitFactorization{Factors: 20, Seed: 42}
+		implicit.TrainWALSConcurrent(matrix)
+		fmt.Printf("Recomendaciones ALS implícito para %s: %v\n", user, implicit.Recommend(user, nil, k))
+	})
+	utils.MeasureExecutionTime("FCBPRConcurrent", func() {
+		implicit := &fc.ImplicitFactorization{Factors: 20, Iterations: 20, Seed: 42}
+		implicit.TrainBPRConcurrent(matrix)
+		fmt.Printf("Recomendaciones BPR para %s: %v\n", user, implicit.Recommend(user, nil, k))
+	})
+
+	// Cadena de recomendadores para usuarios nuevos o con pocas
+	// calificaciones: basado en usuarios, basado en ítems, modelo de sesgos
+	// y, por último, rankings no personalizados
+	utils.MeasureExecutionTime("FCFallback", func() {
+		baseline := &fc.BiasBaseline{}
+		baseline.Train(matrix)
+		chain := fc.Fallback{
+			{Recommender: fc.RecommenderFunc(func(user string, rated map[string]float64, k int) []string {
+				return fc.RecommendCSR(matrix, user, k, userBased)
+			}), MinRatings: 5},
+			{Recommender: fc.RecommenderFunc(func(user string, rated map[string]float64, k int) []string {
+				return neighbors.Recommend(rated, k)
+			}), MinRatings: 1},
+			{Recommender: baseline, MinRatings: 1},
+			{Recommender: fc.NewBayesianAverage(matrix, 0)},
+			{Recommender: fc.NewPopularity(matrix)},
+		}
+		fmt.Printf("Recomendaciones en cadena para %s: %v\n", user, chain.Recommend(user, matrix.UserRatings(user), k))
+		fmt.Printf("Recomendaciones en cadena para un usuario nuevo: %v\n", chain.Recommend("UserNuevo", nil, k))
+	})
+
+	// Evaluación fuera de línea dejando fuera una calificación por usuario
+	utils.MeasureExecutionTime("FCEvaluation", func() {
+		split, err := fc.LeaveOneOutSplit(df_ratings[1:], parseRating, 42)
+		if err != nil {
+			fmt.Println("Error al dividir las calificaciones:", err)
+			return
+		}
+		train := fc.NewRatingsConcurrent()
+		if err := train.LoadRatingsConcurrent(split.Train, parseRating, 0); err != nil {
+			fmt.Println("Error al convertir la calificación:", err)
+		}
+		evaluation := fc.Evaluation{K: k}
+
+		userMetrics := evaluation.Run(split, func(user, item string) fc.Prediction {
+			return fc.PredictRatingConcurrent(train, user, item, userBased)
+		}, nil)
+		fmt.Printf("Evaluación basada en usuarios:\n%v\n", userMetrics)
+
+		trainNeighbors := fc.BuildItemNeighborsConcurrent(train, 20, fc.Shrunk{Base: fc.AdjustedCosine{}, Beta: 10}, 0)
+		itemMetrics := evaluation.Run(split, func(user, item string) fc.Prediction {
+			return trainNeighbors.PredictRating(train.UserRatingsConcurrent(user), item)
+		}, func(user string, k int) []string {
+			return fc.RecommendItemBasedConcurrent(trainNeighbors, train, user, k)
+		})
+		fmt.Printf("Evaluación basada en ítems:\n%v\n", itemMetrics)
+	})
 }
\ No newline at end of file
@@ -0,0 +1,134 @@
+package preprocessing
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Dataset is a named, column-oriented view over a numeric table. Missing
+// values are represented as NaN, which is what ParseDataset produces for
+// empty or "NA"-like cells.
+type Dataset struct {
+	Columns []string
+	X       [][]float64
+}
+
+// missingTokens are the cell values treated as missing when parsing records
+var missingTokens = map[string]bool{
+	"":     true,
+	"na":   true,
+	"nan":  true,
+	"null": true,
+	"none": true,
+	"?":    true,
+}
+
+// ParseDataset converts the string records returned by utils.LoadDataset into
+// a Dataset, keeping missing cells as NaN instead of failing on them
+func ParseDataset(columns []string, records [][]string) (*Dataset, error) {
+	X := make([][]float64, len(records))
+	for i, record := range records {
+		if len(record) != len(columns) {
+			return nil, fmt.Errorf(
+				"row %d has %d values, expected %d columns",
+				i, len(record), len(columns),
+			)
+		}
+		row := make([]float64, len(record))
+		for j, val := range record {
+			val = strings.TrimSpace(val)
+			if missingTokens[strings.ToLower(val)] {
+				row[j] = math.NaN()
+				continue
+			}
+			parsed, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				return nil, fmt.Errorf("column %q row %d: %w", columns[j], i, err)
+			}
+			row[j] = parsed
+		}
+		X[i] = row
+	}
+	return &Dataset{Columns: append([]string(nil), columns...), X: X}, nil
+}
+
+// Index returns the position of the named column, or -1 if it is not present
+func (d *Dataset) Index(name string) int {
+	for j, col := range d.Columns {
+		if col == name {
+			return j
+		}
+	}
+	return -1
+}
+
+// Column returns a copy of the named column
+func (d *Dataset) Column(name string) ([]float64, error) {
+	j := d.Index(name)
+	if j < 0 {
+		return nil, fmt.Errorf("unknown column %q", name)
+	}
+	return column(d.X, j), nil
+}
+
+// Clone returns a deep copy of the dataset, so that steps can transform it
+// without modifying their input
+func (d *Dataset) Clone() *Dataset {
+	X := make([][]float64, len(d.X))
+	for i, row := range d.X {
+		X[i] = append([]float64(nil), row...)
+	}
+	return &Dataset{Columns: append([]string(nil), d.Columns...), X: X}
+}
+
+// MissingCounts returns the number of missing values in every column, the
+// equivalent of pandas' isnull().sum()
+func (d *Dataset) MissingCounts() map[string]int {
+	counts := make([]int, len(d.Columns))
+	columnsConcurrent(len(d.Columns), func(j int) {
+		for _, row := range d.X {
+			if math.IsNaN(row[j]) {
+				counts[j]++
+			}
+		}
+	})
+	result := make(map[string]int, len(d.Columns))
+	for j, col := range d.Columns {
+		result[col] = counts[j]
+	}
+	return result
+}
+
+// resolveColumns maps column names to their indices. An empty list selects
+// every column of the dataset.
+func (d *Dataset) resolveColumns(names []string) ([]int, error) {
+	if len(names) == 0 {
+		indices := make([]int, len(d.Columns))
+		for j := range indices {
+			indices[j] = j
+		}
+		return indices, nil
+	}
+	indices := make([]int, len(names))
+	for i, name := range names {
+		j := d.Index(name)
+		if j < 0 {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		indices[i] = j
+	}
+	return indices, nil
+}
+
+// presentValues returns the non-missing values of column j
+func presentValues(X [][]float64, j int) []float64 {
+	values := make([]float64, 0, len(X))
+	for _, row := range X {
+		if !math.IsNaN(row[j]) {
+			values = append(values, row[j])
+		}
+	}
+	return values
+}
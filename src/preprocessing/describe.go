@@ -0,0 +1,71 @@
+package preprocessing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ColumnSummary holds the descriptive statistics of a single column, computed
+// over its non-missing values.
+type ColumnSummary struct {
+	Name    string
+	Count   int
+	Missing int
+	Mean    float64
+	Std     float64
+	Min     float64
+	Q25     float64
+	Median  float64
+	Q75     float64
+	Max     float64
+}
+
+// Summary is the describe-style report of a dataset, one entry per column.
+type Summary []ColumnSummary
+
+// Describe computes the summary statistics of every column in parallel, the
+// equivalent of pandas' describe() extended with the missing-value count
+func Describe(d *Dataset) Summary {
+	summary := make(Summary, len(d.Columns))
+	columnsConcurrent(len(d.Columns), func(j int) {
+		values := presentValues(d.X, j)
+		s := ColumnSummary{
+			Name:    d.Columns[j],
+			Count:   len(values),
+			Missing: len(d.X) - len(values),
+		}
+		if len(values) > 0 {
+			sort.Float64s(values)
+			s.Mean = meanOf(values)
+			if len(values) > 1 {
+				variance := 0.0
+				for _, v := range values {
+					diff := v - s.Mean
+					variance += diff * diff
+				}
+				s.Std = math.Sqrt(variance / float64(len(values)-1))
+			}
+			s.Min = values[0]
+			s.Q25 = quantileSorted(values, 0.25)
+			s.Median = quantileSorted(values, 0.5)
+			s.Q75 = quantileSorted(values, 0.75)
+			s.Max = values[len(values)-1]
+		}
+		summary[j] = s
+	})
+	return summary
+}
+
+// String formats the summary as a table with one row per column
+func (s Summary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-28s %8s %8s %12s %12s %12s %12s %12s %12s %12s\n",
+		"column", "count", "missing", "mean", "std", "min", "25%", "50%", "75%", "max")
+	for _, c := range s {
+		fmt.Fprintf(&b, "%-28s %8d %8d %12.4f %12.4f %12.4f %12.4f %12.4f %12.4f %12.4f\n",
+			c.Name, c.Count, c.Missing, c.Mean, c.Std, c.Min, c.Q25, c.Median, c.Q75, c.Max)
+	}
+	return b.String()
+}
@@ -0,0 +1,355 @@
+package preprocessing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Step is a single stage of a Pipeline. Fit learns whatever the step needs
+// from the dataset, and Transform returns a new dataset with the step applied
+// using only what was learned during Fit.
+type Step interface {
+	Fit(d *Dataset) error
+	Transform(d *Dataset) (*Dataset, error)
+}
+
+// Pipeline chains steps over a dataset. During Fit every step is fitted on
+// the output of the previous one, so that later steps see the data exactly as
+// it will look at inference time.
+type Pipeline struct {
+	Steps []Step
+}
+
+// NewPipeline creates a pipeline running the given steps in order
+func NewPipeline(steps ...Step) *Pipeline {
+	return &Pipeline{Steps: steps}
+}
+
+// Fit fits every step in order on the progressively transformed dataset
+func (p *Pipeline) Fit(d *Dataset) error {
+	_, err := p.FitTransform(d)
+	return err
+}
+
+// FitTransform fits every step and returns the fully transformed dataset
+func (p *Pipeline) FitTransform(d *Dataset) (*Dataset, error) {
+	current := d
+	for i, step := range p.Steps {
+		if err := step.Fit(current); err != nil {
+			return nil, fmt.Errorf("step %d (%T): %w", i, step, err)
+		}
+		next, err := step.Transform(current)
+		if err != nil {
+			return nil, fmt.Errorf("step %d (%T): %w", i, step, err)
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// Transform applies every fitted step in order
+func (p *Pipeline) Transform(d *Dataset) (*Dataset, error) {
+	current := d
+	for i, step := range p.Steps {
+		next, err := step.Transform(current)
+		if err != nil {
+			return nil, fmt.Errorf("step %d (%T): %w", i, step, err)
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// ImputeStrategy selects how an Imputer computes the fill value of a column
+type ImputeStrategy int
+
+const (
+	ImputeMean ImputeStrategy = iota
+	ImputeMedian
+	ImputeConstant
+)
+
+// Imputer replaces missing values in the selected columns (every column if
+// Columns is empty) with the column mean, median or a constant Value.
+type Imputer struct {
+	Columns  []string
+	Strategy ImputeStrategy
+	Value    float64
+	Fill     map[string]float64
+}
+
+// Fit computes the fill value of every selected column concurrently
+func (s *Imputer) Fit(d *Dataset) error {
+	indices, err := d.resolveColumns(s.Columns)
+	if err != nil {
+		return err
+	}
+	fill := make([]float64, len(indices))
+	columnsConcurrent(len(indices), func(i int) {
+		values := presentValues(d.X, indices[i])
+		switch s.Strategy {
+		case ImputeMean:
+			fill[i] = meanOf(values)
+		case ImputeMedian:
+			sort.Float64s(values)
+			fill[i] = quantileSorted(values, 0.5)
+		default:
+			fill[i] = s.Value
+		}
+	})
+	s.Fill = make(map[string]float64, len(indices))
+	for i, j := range indices {
+		s.Fill[d.Columns[j]] = fill[i]
+	}
+	return nil
+}
+
+// Transform replaces missing values with the fitted fill values
+func (s *Imputer) Transform(d *Dataset) (*Dataset, error) {
+	if s.Fill == nil {
+		return nil, errors.New("imputer must be fitted before use")
+	}
+	result := d.Clone()
+	for name, value := range s.Fill {
+		j := result.Index(name)
+		if j < 0 {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		for _, row := range result.X {
+			if math.IsNaN(row[j]) {
+				row[j] = value
+			}
+		}
+	}
+	return result, nil
+}
+
+// OutlierClipper clips the selected columns (every column if Columns is
+// empty) to the [Lower, Upper] quantile range observed during Fit. Missing
+// values are left untouched.
+type OutlierClipper struct {
+	Columns []string
+	Lower   float64
+	Upper   float64
+	Bounds  map[string][2]float64
+}
+
+// Fit computes the clipping bounds of every selected column concurrently
+func (s *OutlierClipper) Fit(d *Dataset) error {
+	if s.Lower < 0 || s.Upper > 1 || s.Lower >= s.Upper {
+		return fmt.Errorf("invalid quantile range [%v, %v]", s.Lower, s.Upper)
+	}
+	indices, err := d.resolveColumns(s.Columns)
+	if err != nil {
+		return err
+	}
+	bounds := make([][2]float64, len(indices))
+	columnsConcurrent(len(indices), func(i int) {
+		values := presentValues(d.X, indices[i])
+		sort.Float64s(values)
+		bounds[i] = [2]float64{quantileSorted(values, s.Lower), quantileSorted(values, s.Upper)}
+	})
+	s.Bounds = make(map[string][2]float64, len(indices))
+	for i, j := range indices {
+		s.Bounds[d.Columns[j]] = bounds[i]
+	}
+	return nil
+}
+
+// Transform clips the selected columns to the fitted bounds
+func (s *OutlierClipper) Transform(d *Dataset) (*Dataset, error) {
+	if s.Bounds == nil {
+		return nil, errors.New("clipper must be fitted before use")
+	}
+	result := d.Clone()
+	for name, bound := range s.Bounds {
+		j := result.Index(name)
+		if j < 0 {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		for _, row := range result.X {
+			if row[j] < bound[0] {
+				row[j] = bound[0]
+			} else if row[j] > bound[1] {
+				row[j] = bound[1]
+			}
+		}
+	}
+	return result, nil
+}
+
+// DropColumns removes the listed columns from the dataset
+type DropColumns struct {
+	Columns []string
+}
+
+// Fit checks that every column to drop exists
+func (s *DropColumns) Fit(d *Dataset) error {
+	_, err := d.resolveColumns(s.Columns)
+	return err
+}
+
+// Transform returns the dataset without the dropped columns
+func (s *DropColumns) Transform(d *Dataset) (*Dataset, error) {
+	drop := make(map[string]bool, len(s.Columns))
+	for _, name := range s.Columns {
+		drop[name] = true
+	}
+	var keep []int
+	result := &Dataset{}
+	for j, name := range d.Columns {
+		if !drop[name] {
+			keep = append(keep, j)
+			result.Columns = append(result.Columns, name)
+		}
+	}
+	result.X = make([][]float64, len(d.X))
+	for i, row := range d.X {
+		newRow := make([]float64, len(keep))
+		for k, j := range keep {
+			newRow[k] = row[j]
+		}
+		result.X[i] = newRow
+	}
+	return result, nil
+}
+
+// OneHotEncoder replaces each of the listed categorical columns with one
+// indicator column per category seen during Fit, named "<column>=<value>".
+// Values not seen during Fit encode as all zeros. At least one column must be
+// listed.
+type OneHotEncoder struct {
+	Columns    []string
+	Categories map[string][]float64
+}
+
+// Fit collects the sorted distinct values of every listed column concurrently
+func (s *OneHotEncoder) Fit(d *Dataset) error {
+	if len(s.Columns) == 0 {
+		return errors.New("encoder requires at least one column")
+	}
+	indices, err := d.resolveColumns(s.Columns)
+	if err != nil {
+		return err
+	}
+	categories := make([][]float64, len(indices))
+	columnsConcurrent(len(indices), func(i int) {
+		seen := make(map[float64]bool)
+		for _, v := range presentValues(d.X, indices[i]) {
+			if !seen[v] {
+				seen[v] = true
+				categories[i] = append(categories[i], v)
+			}
+		}
+		sort.Float64s(categories[i])
+	})
+	s.Categories = make(map[string][]float64, len(indices))
+	for i, j := range indices {
+		s.Categories[d.Columns[j]] = categories[i]
+	}
+	return nil
+}
+
+// Transform expands the encoded columns in place of the originals
+func (s *OneHotEncoder) Transform(d *Dataset) (*Dataset, error) {
+	if s.Categories == nil {
+		return nil, errors.New("encoder must be fitted before use")
+	}
+	for name := range s.Categories {
+		if d.Index(name) < 0 {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+	}
+	result := &Dataset{}
+	for _, name := range d.Columns {
+		levels, encoded := s.Categories[name]
+		if !encoded {
+			result.Columns = append(result.Columns, name)
+			continue
+		}
+		for _, level := range levels {
+			result.Columns = append(result.Columns, name+"="+strconv.FormatFloat(level, 'g', -1, 64))
+		}
+	}
+	result.X = make([][]float64, len(d.X))
+	for i, row := range d.X {
+		newRow := make([]float64, 0, len(result.Columns))
+		for j, name := range d.Columns {
+			levels, encoded := s.Categories[name]
+			if !encoded {
+				newRow = append(newRow, row[j])
+				continue
+			}
+			for _, level := range levels {
+				if row[j] == level {
+					newRow = append(newRow, 1)
+				} else {
+					newRow = append(newRow, 0)
+				}
+			}
+		}
+		result.X[i] = newRow
+	}
+	return result, nil
+}
+
+// ScaleColumns applies a Scaler to the selected columns (every column if
+// Columns is empty), leaving the rest of the dataset unchanged.
+type ScaleColumns struct {
+	Columns []string
+	Scaler  Scaler
+	fitted  []string
+}
+
+// Fit fits the scaler on the selected columns
+func (s *ScaleColumns) Fit(d *Dataset) error {
+	if s.Scaler == nil {
+		return errors.New("scale step requires a scaler")
+	}
+	indices, err := d.resolveColumns(s.Columns)
+	if err != nil {
+		return err
+	}
+	s.fitted = make([]string, len(indices))
+	for i, j := range indices {
+		s.fitted[i] = d.Columns[j]
+	}
+	return s.Scaler.Fit(selectColumns(d.X, indices))
+}
+
+// Transform scales the selected columns with the fitted scaler
+func (s *ScaleColumns) Transform(d *Dataset) (*Dataset, error) {
+	if s.fitted == nil {
+		return nil, errors.New("scale step must be fitted before use")
+	}
+	indices, err := d.resolveColumns(s.fitted)
+	if err != nil {
+		return nil, err
+	}
+	scaled, err := s.Scaler.Transform(selectColumns(d.X, indices))
+	if err != nil {
+		return nil, err
+	}
+	result := d.Clone()
+	for i, row := range result.X {
+		for k, j := range indices {
+			row[j] = scaled[i][k]
+		}
+	}
+	return result, nil
+}
+
+// selectColumns copies the given columns of X into a new table
+func selectColumns(X [][]float64, indices []int) [][]float64 {
+	result := make([][]float64, len(X))
+	for i, row := range X {
+		result[i] = make([]float64, len(indices))
+		for k, j := range indices {
+			result[i][k] = row[j]
+		}
+	}
+	return result
+}
@@ -0,0 +1,210 @@
+package preprocessing
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// equalRows compares two tables treating NaN as equal to NaN
+func equalRows(got, want [][]float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range want {
+		if len(got[i]) != len(want[i]) {
+			return false
+		}
+		for j := range want[i] {
+			if got[i][j] != want[i][j] && !(math.IsNaN(got[i][j]) && math.IsNaN(want[i][j])) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestPipeline(t *testing.T) {
+	nan := math.NaN()
+	train := &Dataset{
+		Columns: []string{"age", "color", "income"},
+		X:       [][]float64{{20, 1, 10}, {nan, 2, 20}, {40, 1, 30}, {60, 3, 1000}},
+	}
+	test := &Dataset{
+		Columns: []string{"age", "color", "income"},
+		X:       [][]float64{{nan, 4, 5}, {30, 2, 2000}},
+	}
+	cases := []struct {
+		name        string
+		steps       func() []Step
+		wantColumns []string
+		wantTrain   [][]float64
+		wantTest    [][]float64
+		wantErr     string
+	}{
+		{
+			name: "impute clip encode",
+			steps: func() []Step {
+				return []Step{
+					&Imputer{Columns: []string{"age"}, Strategy: ImputeMedian},
+					&OutlierClipper{Columns: []string{"income"}, Lower: 0, Upper: 0.5},
+					&OneHotEncoder{Columns: []string{"color"}},
+				}
+			},
+			wantColumns: []string{"age", "color=1", "color=2", "color=3", "income"},
+			wantTrain:   [][]float64{{20, 1, 0, 0, 10}, {40, 0, 1, 0, 20}, {40, 1, 0, 0, 25}, {60, 0, 0, 1, 25}},
+			wantTest:    [][]float64{{40, 0, 0, 0, 10}, {30, 0, 1, 0, 25}},
+		},
+		{
+			name: "drop and scale",
+			steps: func() []Step {
+				return []Step{
+					&DropColumns{Columns: []string{"color", "income"}},
+					&ScaleColumns{Scaler: &MinMaxScaler{}},
+				}
+			},
+			wantColumns: []string{"age"},
+			wantTrain:   [][]float64{{0}, {nan}, {0.5}, {1}},
+			wantTest:    [][]float64{{nan}, {0.25}},
+		},
+		{
+			name: "failing step",
+			steps: func() []Step {
+				return []Step{&Imputer{}, &DropColumns{Columns: []string{"missing"}}}
+			},
+			wantErr: "step 1",
+		},
+	}
+	for _, c := range cases {
+		p := NewPipeline(c.steps()...)
+		fitted, err := p.FitTransform(train)
+		if c.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("%s: FitTransform error = %v, want %q", c.name, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !reflect.DeepEqual(fitted.Columns, c.wantColumns) || !equalRows(fitted.X, c.wantTrain) {
+			t.Errorf("%s: FitTransform = %v %v, want %v %v", c.name, fitted.Columns, fitted.X, c.wantColumns, c.wantTrain)
+		}
+		again, err := p.Transform(train)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !equalRows(again.X, fitted.X) {
+			t.Errorf("%s: Transform after Fit = %v, FitTransform %v", c.name, again.X, fitted.X)
+		}
+		transformed, err := p.Transform(test)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !equalRows(transformed.X, c.wantTest) {
+			t.Errorf("%s: Transform = %v, want %v", c.name, transformed.X, c.wantTest)
+		}
+	}
+	if train.X[1][0] == 40 {
+		t.Error("pipeline modified its input dataset")
+	}
+}
+
+func TestOneHotEncoder(t *testing.T) {
+	nan := math.NaN()
+	train := &Dataset{Columns: []string{"a", "b"}, X: [][]float64{{2, 7}, {1, 8}, {2, nan}}}
+	cases := []struct {
+		name        string
+		columns     []string
+		input       [][]float64
+		wantColumns []string
+		wantX       [][]float64
+		wantErr     bool
+	}{
+		{
+			name:        "round trip",
+			columns:     []string{"a"},
+			input:       train.X,
+			wantColumns: []string{"a=1", "a=2", "b"},
+			wantX:       [][]float64{{0, 1, 7}, {1, 0, 8}, {0, 1, nan}},
+		},
+		{
+			name:        "unseen and missing categories",
+			columns:     []string{"a", "b"},
+			input:       [][]float64{{3, 7}, {1, nan}},
+			wantColumns: []string{"a=1", "a=2", "b=7", "b=8"},
+			wantX:       [][]float64{{0, 0, 1, 0}, {1, 0, 0, 0}},
+		},
+		{name: "no columns", wantErr: true},
+		{name: "unknown column", columns: []string{"c"}, wantErr: true},
+	}
+	for _, c := range cases {
+		encoder := &OneHotEncoder{Columns: c.columns}
+		err := encoder.Fit(train)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: Fit returned no error", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		encoded, err := encoder.Transform(&Dataset{Columns: train.Columns, X: c.input})
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !reflect.DeepEqual(encoded.Columns, c.wantColumns) || !equalRows(encoded.X, c.wantX) {
+			t.Errorf("%s: Transform = %v %v, want %v %v", c.name, encoded.Columns, encoded.X, c.wantColumns, c.wantX)
+		}
+	}
+	if _, err := (&OneHotEncoder{Columns: []string{"a"}}).Transform(train); err == nil {
+		t.Error("Transform before Fit returned no error")
+	}
+}
+
+func TestOutlierClipper(t *testing.T) {
+	nan := math.NaN()
+	d := &Dataset{Columns: []string{"x", "y"}, X: [][]float64{{0, 1}, {10, 2}, {20, nan}, {30, 3}, {400, 4}}}
+	cases := []struct {
+		name    string
+		clipper OutlierClipper
+		wantX   [][]float64
+		wantErr bool
+	}{
+		{
+			name:    "inner quartiles of one column",
+			clipper: OutlierClipper{Columns: []string{"x"}, Lower: 0.25, Upper: 0.75},
+			wantX:   [][]float64{{10, 1}, {10, 2}, {20, nan}, {30, 3}, {30, 4}},
+		},
+		{
+			name:    "every column",
+			clipper: OutlierClipper{Lower: 0, Upper: 0.5},
+			wantX:   [][]float64{{0, 1}, {10, 2}, {20, nan}, {20, 2.5}, {20, 2.5}},
+		},
+		{name: "full range", clipper: OutlierClipper{Lower: 0, Upper: 1}, wantX: d.X},
+		{name: "reversed range", clipper: OutlierClipper{Lower: 0.9, Upper: 0.1}, wantErr: true},
+		{name: "out of range", clipper: OutlierClipper{Lower: -0.1, Upper: 1}, wantErr: true},
+	}
+	for _, c := range cases {
+		clipper := c.clipper
+		err := clipper.Fit(d)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: Fit returned no error", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		clipped, err := clipper.Transform(d)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !equalRows(clipped.X, c.wantX) {
+			t.Errorf("%s: Transform = %v, want %v", c.name, clipped.X, c.wantX)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package preprocessing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Scaler is implemented by every column-wise feature transformer. Fit learns
+// the per-column statistics from X, Transform applies them and
+// InverseTransform undoes them, so that values can be mapped back to the
+// original feature space. Missing values, held as NaN, are left out of the
+// statistics and pass through Transform unchanged, to be filled later by an
+// Imputer.
+type Scaler interface {
+	Fit(X [][]float64) error
+	Transform(X [][]float64) ([][]float64, error)
+	InverseTransform(X [][]float64) ([][]float64, error)
+}
+
+// StandardScaler centers every column on its mean and scales it to unit
+// variance.
+type StandardScaler struct {
+	Mean  []float64 `json:"mean"`
+	Scale []float64 `json:"scale"`
+}
+
+// MinMaxScaler maps every column linearly onto the [0, 1] range observed
+// during Fit.
+type MinMaxScaler struct {
+	Min   []float64 `json:"min"`
+	Scale []float64 `json:"scale"`
+}
+
+// RobustScaler centers every column on its median and scales it by the
+// interquartile range, which makes it insensitive to outliers.
+type RobustScaler struct {
+	Median []float64 `json:"median"`
+	Scale  []float64 `json:"scale"`
+}
+
+// Fit computes the mean and standard deviation of the present values of every
+// column concurrently
+func (s *StandardScaler) Fit(X [][]float64) error {
+	if err := checkFit(X); err != nil {
+		return err
+	}
+	numCols := len(X[0])
+	s.Mean = make([]float64, numCols)
+	s.Scale = make([]float64, numCols)
+	columnsConcurrent(numCols, func(j int) {
+		col := presentValues(X, j)
+		mean := meanOf(col)
+		variance := 0.0
+		for _, v := range col {
+			diff := v - mean
+			variance += diff * diff
+		}
+		s.Mean[j] = mean
+		s.Scale[j] = 1
+		if len(col) > 0 {
+			s.Scale[j] = safeScale(math.Sqrt(variance / float64(len(col))))
+		}
+	})
+	return nil
+}
+
+// Transform standardizes X with the statistics learned during Fit
+func (s *StandardScaler) Transform(X [][]float64) ([][]float64, error) {
+	return applyColumns(X, s.Mean, func(j int, v float64) float64 {
+		return (v - s.Mean[j]) / s.Scale[j]
+	})
+}
+
+// InverseTransform maps standardized values back to the original scale
+func (s *StandardScaler) InverseTransform(X [][]float64) ([][]float64, error) {
+	return applyColumns(X, s.Mean, func(j int, v float64) float64 {
+		return v*s.Scale[j] + s.Mean[j]
+	})
+}
+
+// Fit computes the minimum and range of the present values of every column
+// concurrently
+func (s *MinMaxScaler) Fit(X [][]float64) error {
+	if err := checkFit(X); err != nil {
+		return err
+	}
+	numCols := len(X[0])
+	s.Min = make([]float64, numCols)
+	s.Scale = make([]float64, numCols)
+	columnsConcurrent(numCols, func(j int) {
+		col := presentValues(X, j)
+		if len(col) == 0 {
+			s.Scale[j] = 1
+			return
+		}
+		min, max := col[0], col[0]
+		for _, v := range col[1:] {
+			min = math.Min(min, v)
+			max = math.Max(max, v)
+		}
+		s.Min[j] = min
+		s.Scale[j] = safeScale(max - min)
+	})
+	return nil
+}
+
+// Transform rescales X onto the range learned during Fit
+func (s *MinMaxScaler) Transform(X [][]float64) ([][]float64, error) {
+	return applyColumns(X, s.Min, func(j int, v float64) float64 {
+		return (v - s.Min[j]) / s.Scale[j]
+	})
+}
+
+// InverseTransform maps rescaled values back to the original range
+func (s *MinMaxScaler) InverseTransform(X [][]float64) ([][]float64, error) {
+	return applyColumns(X, s.Min, func(j int, v float64) float64 {
+		return v*s.Scale[j] + s.Min[j]
+	})
+}
+
+// Fit computes the median and interquartile range of the present values of
+// every column concurrently
+func (s *RobustScaler) Fit(X [][]float64) error {
+	if err := checkFit(X); err != nil {
+		return err
+	}
+	numCols := len(X[0])
+	s.Median = make([]float64, numCols)
+	s.Scale = make([]float64, numCols)
+	columnsConcurrent(numCols, func(j int) {
+		col := presentValues(X, j)
+		sort.Float64s(col)
+		s.Median[j] = quantileSorted(col, 0.5)
+		s.Scale[j] = safeScale(quantileSorted(col, 0.75) - quantileSorted(col, 0.25))
+	})
+	return nil
+}
+
+// Transform centers and scales X with the statistics learned during Fit
+func (s *RobustScaler) Transform(X [][]float64) ([][]float64, error) {
+	return applyColumns(X, s.Median, func(j int, v float64) float64 {
+		return (v - s.Median[j]) / s.Scale[j]
+	})
+}
+
+// InverseTransform maps scaled values back to the original scale
+func (s *RobustScaler) InverseTransform(X [][]float64) ([][]float64, error) {
+	return applyColumns(X, s.Median, func(j int, v float64) float64 {
+		return v*s.Scale[j] + s.Median[j]
+	})
+}
+
+// scalerFile is the on-disk representation of a fitted scaler. Kind records
+// the concrete type so that LoadScaler can rebuild it.
+type scalerFile struct {
+	Kind   string          `json:"kind"`
+	Params json.RawMessage `json:"params"`
+}
+
+// SaveScaler writes a fitted scaler to path as JSON, so that the scaling used
+// at training time can be reproduced at inference
+func SaveScaler(path string, s Scaler) error {
+	var kind string
+	switch s.(type) {
+	case *StandardScaler:
+		kind = "standard"
+	case *MinMaxScaler:
+		kind = "minmax"
+	case *RobustScaler:
+		kind = "robust"
+	default:
+		return fmt.Errorf("unsupported scaler type %T", s)
+	}
+	params, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(scalerFile{Kind: kind, Params: params}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadScaler reads a scaler previously written with SaveScaler
+func LoadScaler(path string) (Scaler, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file scalerFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	var s Scaler
+	switch file.Kind {
+	case "standard":
+		s = &StandardScaler{}
+	case "minmax":
+		s = &MinMaxScaler{}
+	case "robust":
+		s = &RobustScaler{}
+	default:
+		return nil, fmt.Errorf("unknown scaler kind %q", file.Kind)
+	}
+	if err := json.Unmarshal(file.Params, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func checkFit(X [][]float64) error {
+	if len(X) == 0 || len(X[0]) == 0 {
+		return errors.New("cannot fit on an empty dataset")
+	}
+	return nil
+}
+
+// applyColumns returns a copy of X with fn applied to every value. The
+// fitted statistics are used to validate that the scaler was fitted and that
+// X has the expected width.
+func applyColumns(X [][]float64, fitted []float64, fn func(j int, v float64) float64) ([][]float64, error) {
+	if fitted == nil {
+		return nil, errors.New("scaler must be fitted before use")
+	}
+	result := make([][]float64, len(X))
+	for i, row := range X {
+		if len(row) != len(fitted) {
+			return nil, fmt.Errorf(
+				"row %d has %d columns, scaler was fitted on %d",
+				i, len(row), len(fitted),
+			)
+		}
+		result[i] = make([]float64, len(row))
+		for j, v := range row {
+			result[i][j] = fn(j, v)
+		}
+	}
+	return result, nil
+}
+
+// columnsConcurrent runs fn once per column, each in its own goroutine
+func columnsConcurrent(numCols int, fn func(j int)) {
+	var wg sync.WaitGroup
+	for j := 0; j < numCols; j++ {
+		wg.Add(1)
+		go func(j int) {
+			defer wg.Done()
+			fn(j)
+		}(j)
+	}
+	wg.Wait()
+}
+
+// column copies column j of X into a new slice
+func column(X [][]float64, j int) []float64 {
+	col := make([]float64, len(X))
+	for i, row := range X {
+		col[i] = row[j]
+	}
+	return col
+}
+
+func meanOf(data []float64) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range data {
+		sum += v
+	}
+	return sum / float64(len(data))
+}
+
+// quantileSorted returns the q quantile of an already sorted slice, linearly
+// interpolating between the closest ranks
+func quantileSorted(sorted []float64, q float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	pos := q * float64(n-1)
+	lower := int(pos)
+	if lower+1 >= n {
+		return sorted[lower]
+	}
+	weight := pos - float64(lower)
+	return sorted[lower]*(1-weight) + sorted[lower+1]*weight
+}
+
+// safeScale avoids dividing by zero on constant columns
+func safeScale(scale float64) float64 {
+	if scale == 0 {
+		return 1
+	}
+	return scale
+}
@@ -0,0 +1,37 @@
+package preprocessing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScalersSkipMissing(t *testing.T) {
+	nan := math.NaN()
+	X := [][]float64{{1, nan}, {nan, nan}, {3, nan}, {5, nan}}
+	cases := []struct {
+		name   string
+		scaler Scaler
+		want   []float64
+	}{
+		{name: "standard", scaler: &StandardScaler{}, want: []float64{-1.224744871391589, nan, 0, 1.224744871391589}},
+		{name: "minmax", scaler: &MinMaxScaler{}, want: []float64{0, nan, 0.5, 1}},
+		{name: "robust", scaler: &RobustScaler{}, want: []float64{-1, nan, 0, 1}},
+	}
+	for _, c := range cases {
+		if err := c.scaler.Fit(X); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		got, err := c.scaler.Transform(X)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		for i, row := range got {
+			if math.IsNaN(c.want[i]) != math.IsNaN(row[0]) || (!math.IsNaN(row[0]) && math.Abs(row[0]-c.want[i]) > 1e-12) {
+				t.Errorf("%s: row %d column 0 = %g, want %g", c.name, i, row[0], c.want[i])
+			}
+			if !math.IsNaN(row[1]) {
+				t.Errorf("%s: row %d missing column = %g, want NaN", c.name, i, row[1])
+			}
+		}
+	}
+}